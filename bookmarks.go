@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/xqbumu/go-novel-reader/config"
+	"github.com/xqbumu/go-novel-reader/playback"
+)
+
+// maxSnippetRunes limits the text kept with a bookmark.
+const maxSnippetRunes = 160
+
+// handleBookmark manages bookmarks of the active novel.
+func handleBookmark(args []string) {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	sub, args := args[0], args[1:]
+	if activeNovel == nil && sub != "list" {
+		log.Fatal("Error: No active novel selected. Use 'switch <novel>' first.")
+	}
+
+	switch sub {
+	case "add":
+		fs := flag.NewFlagSet("bookmark add", flag.ExitOnError)
+		note := fs.String("note", "", "note to keep with the bookmark")
+		chapter := fs.Int("chapter", 0, "chapter to mark (1-based); default is the current position")
+		segment := fs.Int("segment", -1, "segment to mark within the chapter; default is the current position")
+		args = parseCommandFlags(fs, args)
+		progInfo := currentProgress()
+		chapterIndex, segmentIndex := progInfo.LastReadChapterIndex, progInfo.LastReadSegmentIndex
+		if *chapter > 0 {
+			chapterIndex, segmentIndex = *chapter-1, 0
+		}
+		if *segment >= 0 {
+			segmentIndex = *segment
+		}
+		name := strings.Join(args, " ")
+		addBookmark(name, *note, chapterIndex, segmentIndex)
+	case "list", "ls":
+		all := len(args) > 0 && args[0] == "--all"
+		if activeNovel == nil && !all {
+			log.Fatal("Error: No active novel selected. Use 'switch <novel>' first, or 'bookmark list --all'.")
+		}
+		novelID := ""
+		if !all {
+			novelID = activeNovel.ID
+		}
+		listBookmarks(novelID)
+	case "rm", "remove":
+		if len(args) < 1 {
+			log.Fatal("Error: bookmark rm requires a bookmark name.")
+		}
+		name := strings.Join(args, " ")
+		if err := store.RemoveBookmark(activeNovel.ID, name); err != nil {
+			if errors.Is(err, config.ErrNotFound) {
+				log.Fatalf("Error: No bookmark named '%s' in '%s'.", name, activeNovel.Title)
+			}
+			log.Fatalf("Error removing bookmark: %v", err)
+		}
+		fmt.Printf("Removed bookmark '%s'.\n", name)
+	case "goto":
+		if len(args) < 1 {
+			log.Fatal("Error: bookmark goto requires a bookmark name.")
+		}
+		b := mustFindBookmark(strings.Join(args, " "))
+		progInfo := currentProgress()
+		progInfo.LastReadChapterIndex = b.Chapter
+		progInfo.LastReadSegmentIndex = b.Segment
+		progressDirty = true
+		saveProgress()
+		fmt.Printf("Jumped to bookmark '%s': Chapter %d, Segment %d\n", b.Name, b.Chapter+1, b.Segment)
+		handleRead(nil)
+	case "export":
+		var out io.Writer = os.Stdout
+		if len(args) > 0 {
+			f, err := os.Create(args[0])
+			if err != nil {
+				log.Fatalf("Error creating %s: %v", args[0], err)
+			}
+			defer f.Close()
+			out = f
+		}
+		if err := exportBookmarks(out); err != nil {
+			log.Fatalf("Error exporting bookmarks: %v", err)
+		}
+		if len(args) > 0 {
+			fmt.Printf("Exported bookmarks of '%s' to %s\n", activeNovel.Title, args[0])
+		}
+	default:
+		log.Fatalf("Error: Unknown bookmark subcommand '%s'. Available: add, list, rm, goto, export", sub)
+	}
+}
+
+// currentProgress returns the active novel's progress entry, creating it if missing.
+func currentProgress() *config.ProgressInfo {
+	progInfo, ok := progressData[activeNovel.FilePath]
+	if !ok {
+		progInfo = &config.ProgressInfo{}
+		progressData[activeNovel.FilePath] = progInfo
+		progressDirty = true
+	}
+	return progInfo
+}
+
+// addBookmark bookmarks a position of the active novel and reports the result. An
+// empty name is replaced by the position, like "12:34" for chapter 12, segment 34.
+func addBookmark(name, note string, chapterIndex, segmentIndex int) {
+	b, err := saveBookmark(name, note, chapterIndex, segmentIndex)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return
+	}
+	fmt.Printf("Bookmarked '%s' at Chapter %d, Segment %d.\n", b.Name, b.Chapter+1, b.Segment)
+}
+
+// saveBookmark bookmarks a position of the active novel without printing anything.
+func saveBookmark(name, note string, chapterIndex, segmentIndex int) (config.Bookmark, error) {
+	loadActiveNovelChapters()
+	text := activeText()
+	if err := text.check(playback.Position{Chapter: chapterIndex, Segment: segmentIndex}); err != nil {
+		return config.Bookmark{}, err
+	}
+	if name == "" {
+		name = fmt.Sprintf("%d:%d", chapterIndex+1, segmentIndex)
+	}
+	b := config.Bookmark{
+		NovelID:   activeNovel.ID,
+		Name:      name,
+		Chapter:   chapterIndex,
+		Segment:   segmentIndex,
+		Snippet:   snippet(text.filter(text.segments[chapterIndex][segmentIndex])),
+		Note:      note,
+		CreatedAt: time.Now(),
+	}
+	if err := store.AddBookmark(b); err != nil {
+		if errors.Is(err, config.ErrExists) {
+			return b, fmt.Errorf("a bookmark named '%s' already exists; pick another name", name)
+		}
+		return b, fmt.Errorf("saving bookmark: %w", err)
+	}
+	return b, nil
+}
+
+// snippet shortens text to its first maxSnippetRunes characters on one line.
+func snippet(text string) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) <= maxSnippetRunes {
+		return string(runes)
+	}
+	return string(runes[:maxSnippetRunes]) + "…"
+}
+
+// listBookmarks prints the bookmarks of one novel, or all novels if novelID is empty.
+func listBookmarks(novelID string) {
+	bookmarks, err := store.Bookmarks(novelID)
+	if err != nil {
+		log.Fatalf("Error loading bookmarks: %v", err)
+	}
+	if len(bookmarks) == 0 {
+		fmt.Println("No bookmarks. Use 'bookmark add [name]' or type 'b' and Enter while reading.")
+		return
+	}
+	for _, b := range bookmarks {
+		prefix := ""
+		if novelID == "" {
+			prefix = novelTitle(b.NovelID) + " / "
+		}
+		fmt.Printf("  %s%s: Chapter %d, Segment %d (%s)\n", prefix, b.Name, b.Chapter+1, b.Segment, b.CreatedAt.Local().Format(time.DateTime))
+		if b.Snippet != "" {
+			fmt.Printf("      \"%s\"\n", b.Snippet)
+		}
+		if b.Note != "" {
+			fmt.Printf("      Note: %s\n", b.Note)
+		}
+	}
+}
+
+// mustFindBookmark returns the active novel's bookmark with the given name, or exits.
+func mustFindBookmark(name string) config.Bookmark {
+	bookmarks, err := store.Bookmarks(activeNovel.ID)
+	if err != nil {
+		log.Fatalf("Error loading bookmarks: %v", err)
+	}
+	for _, b := range bookmarks {
+		if b.Name == name {
+			return b
+		}
+	}
+	log.Fatalf("Error: No bookmark named '%s' in '%s'. See 'bookmark list'.", name, activeNovel.Title)
+	return config.Bookmark{}
+}
+
+// exportBookmarks writes the active novel's bookmarks and notes as Markdown.
+func exportBookmarks(w io.Writer) error {
+	bookmarks, err := store.Bookmarks(activeNovel.ID)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Bookmarks: %s\n", activeNovel.Title)
+	for _, bm := range bookmarks {
+		title := ""
+		if bm.Chapter < len(activeNovel.ChapterTitles) {
+			title = ": " + activeNovel.ChapterTitles[bm.Chapter]
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", bm.Name)
+		fmt.Fprintf(&b, "*Chapter %d%s, segment %d — %s*\n", bm.Chapter+1, title, bm.Segment, bm.CreatedAt.Local().Format("2006-01-02 15:04"))
+		if bm.Snippet != "" {
+			fmt.Fprintf(&b, "\n> %s\n", bm.Snippet)
+		}
+		if bm.Note != "" {
+			fmt.Fprintf(&b, "\n%s\n", bm.Note)
+		}
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// bookmarkInput lets 'read' bookmark the segment being spoken when 'b' [name] and
+// Enter are typed. Its listener only asks the engine to mark the segment; the
+// bookmark is saved by Handle, on the engine's goroutine like the other sinks.
+type bookmarkInput struct {
+	controls chan playback.Control
+	names    chan string // Names of the marks asked for, in order
+}
+
+func newBookmarkInput() *bookmarkInput {
+	return &bookmarkInput{controls: make(chan playback.Control), names: make(chan string, 1)}
+}
+
+func (in *bookmarkInput) Handle(e playback.Event) {
+	if e.Kind == playback.Marked {
+		addBookmark(<-in.names, "", e.Pos.Chapter, e.Pos.Segment)
+	}
+}
+
+// listen handles the lines typed on stdin until it ends.
+func (in *bookmarkInput) listen() {
+	fmt.Println("(Type 'b' [name] and Enter to bookmark the segment being spoken.)")
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "b", "bookmark":
+			in.names <- strings.Join(fields[1:], " ")
+			in.controls <- playback.Control{Op: playback.Mark}
+		default:
+			fmt.Printf("Unknown key '%s'. Type 'b' [name] and Enter to bookmark this segment.\n", fields[0])
+		}
+	}
+}
+
+// removeBookmarks deletes all bookmarks of a novel.
+func removeBookmarks(novelID string) {
+	bookmarks, err := store.Bookmarks(novelID)
+	if err != nil {
+		log.Printf("Error loading bookmarks: %v", err)
+		return
+	}
+	for _, b := range bookmarks {
+		if err := store.RemoveBookmark(novelID, b.Name); err != nil {
+			log.Printf("Error removing bookmark '%s': %v", b.Name, err)
+		}
+	}
+	if len(bookmarks) > 0 {
+		fmt.Printf("Removed %d bookmarks.\n", len(bookmarks))
+	}
+}
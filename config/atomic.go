@@ -0,0 +1,194 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// errNoData is returned by readJSONFile when neither a file nor its backups hold valid data.
+var errNoData = errors.New("no valid data")
+
+// maxBackups is the number of previous versions kept for each data file.
+const maxBackups = 5
+
+// backupTimeFormat names backups so they sort chronologically.
+const backupTimeFormat = "20060102T150405.000000000"
+
+// backupDir returns the directory holding the backups of a data file.
+func backupDir(path string) string {
+	return filepath.Join(filepath.Dir(path), "backups")
+}
+
+// writeFileAtomic replaces path with data so that a crash or full disk never leaves a
+// truncated file: data goes to a temporary file in the same directory, which is synced
+// and then renamed over path. The previous content is kept as a rotating backup.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+
+	if err := backupFile(path); err != nil {
+		log.Printf("Warning: Could not back up %s: %v", path, err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir flushes a directory entry change (like a rename) to disk.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// backupFile keeps the current content of path in the backup directory and prunes
+// all but the newest maxBackups backups. A missing path is not an error.
+func backupFile(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	dir := backupDir(path)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+	backup := filepath.Join(dir, filepath.Base(path)+"."+time.Now().Format(backupTimeFormat))
+	// A hard link is instant and atomic; the rename that follows leaves it pointing at the old data.
+	if err := os.Link(path, backup); err != nil {
+		if err := copyFile(path, backup); err != nil {
+			return err
+		}
+	}
+
+	backups, err := listBackups(path)
+	if err != nil {
+		return err
+	}
+	for len(backups) > maxBackups {
+		if err := os.Remove(backups[len(backups)-1]); err != nil {
+			return err
+		}
+		backups = backups[:len(backups)-1]
+	}
+	return nil
+}
+
+// listBackups returns the backups of path, newest first.
+func listBackups(path string) ([]string, error) {
+	entries, err := os.ReadDir(backupDir(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	prefix := filepath.Base(path) + "."
+	var backups []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), prefix) {
+			backups = append(backups, filepath.Join(backupDir(path), e.Name()))
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups, nil
+}
+
+// copyFile copies src to dst, syncing dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// readJSONFile reads path into v. If the file is corrupt (not valid JSON, as left by
+// an interrupted write), it is moved aside into the backup directory for inspection,
+// so the next save doesn't rotate it into the backups in place of a good one. The
+// newest valid backup is then restored to path and used instead, with a warning. If
+// no backup is usable either, v is left untouched with a warning and errNoData is
+// returned, so the caller can start fresh. Errors from reading path are returned as is.
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if json.Valid(data) {
+		return json.Unmarshal(data, v)
+	}
+
+	corrupt := filepath.Join(backupDir(path), "corrupt-"+filepath.Base(path)+"."+time.Now().Format(backupTimeFormat))
+	if err := os.MkdirAll(backupDir(path), 0750); err != nil {
+		return fmt.Errorf("%s is corrupt and could not be moved aside: %w", path, err)
+	}
+	if err := os.Rename(path, corrupt); err != nil {
+		return fmt.Errorf("%s is corrupt and could not be moved aside: %w", path, err)
+	}
+
+	backups, err := listBackups(path)
+	if err != nil {
+		return fmt.Errorf("%s is corrupt and its backups could not be listed: %w", path, err)
+	}
+	for _, backup := range backups {
+		backupData, err := os.ReadFile(backup)
+		if err != nil || !json.Valid(backupData) || json.Unmarshal(backupData, v) != nil {
+			continue
+		}
+		if err := writeFileAtomic(path, backupData, 0640); err != nil {
+			log.Printf("Warning: Could not restore %s from backup %s: %v", path, filepath.Base(backup), err)
+		}
+		log.Printf("Warning: %s is corrupt. Restored from backup %s; the corrupt file was kept as %s.", path, filepath.Base(backup), corrupt)
+		return nil
+	}
+
+	log.Printf("Warning: %s is corrupt and no valid backup was found. Starting fresh; the corrupt file was kept as %s.", path, corrupt)
+	return errNoData
+}
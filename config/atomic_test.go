@@ -0,0 +1,71 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.json")
+	for _, version := range []string{`{"v": 1}`, `{"v": 2}`, `{"v": 3}`} {
+		if err := writeFileAtomic(path, []byte(version), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(path, []byte(`{"v": 4, "trunc`), 0640); err != nil { // An interrupted write
+		t.Fatal(err)
+	}
+
+	var got struct{ V int }
+	if err := readJSONFile(path, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.V != 2 {
+		t.Errorf("restored version %d, want 2 from the newest backup", got.V)
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"v": 2}` {
+		t.Errorf("file after restoring = %s, want the backup", data)
+	}
+	corrupt, _ := filepath.Glob(filepath.Join(backupDir(path), "corrupt-progress.json.*"))
+	if len(corrupt) != 1 {
+		t.Errorf("corrupt copies = %v, want one", corrupt)
+	}
+
+	// Saving again must not rotate the corrupt file into the backups.
+	for range maxBackups {
+		if err := writeFileAtomic(path, []byte(`{"v": 5}`), 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups, err := listBackups(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, backup := range backups {
+		if data, _ := os.ReadFile(backup); !json.Valid(data) {
+			t.Errorf("backup %s is corrupt: %s", filepath.Base(backup), data)
+		}
+	}
+}
+
+func TestReadCorruptFileWithoutBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"novels": `), 0640); err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]any
+	if err := readJSONFile(path, &v); !errors.Is(err, errNoData) {
+		t.Fatalf("readJSONFile() error = %v, want errNoData", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("corrupt file left in place: %v", err)
+	}
+	entries, _ := os.ReadDir(backupDir(path))
+	if len(entries) != 1 || !strings.HasPrefix(entries[0].Name(), "corrupt-config.json.") {
+		t.Errorf("backup directory = %v, want only the corrupt file", entries)
+	}
+}
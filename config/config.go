@@ -2,8 +2,11 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
 
 	"github.com/xqbumu/go-novel-reader/novel"
 )
@@ -12,123 +15,264 @@ import (
 
 // NovelInfo holds metadata for a single novel (progress is stored separately).
 type NovelInfo struct {
-	FilePath      string          `json:"file_path"`
+	ID            string          `json:"id"`                       // Stable short ID used to select the novel in commands
+	Title         string          `json:"title"`                    // Display title, defaults to the file name
+	FilePath      string          `json:"file_path"`                // Novel file, directory of chapter files, or archive
+	ArchiveMember string          `json:"archive_member,omitempty"` // File inside the archive; empty reads every text file of an archive
 	Chapters      []novel.Chapter `json:"-"`                        // Chapters loaded in memory, not saved to JSON directly
 	ChapterTitles []string        `json:"chapter_titles"`           // Save titles to JSON for listing
-	DetectedRegex string          `json:"detected_regex,omitempty"` // Store the name of the detected regex ("chinese", "english", "markdown")
+	ChapterHashes []string        `json:"chapter_hashes,omitempty"` // Content hash per chapter, to re-anchor progress when the file changes
+	FileSize      int64           `json:"file_size,omitempty"`      // File size (total for directories) when chapters were last loaded
+	ModTime       time.Time       `json:"mod_time,omitzero"`        // Newest file modification time when chapters were last loaded
+	Fingerprint   string          `json:"fingerprint,omitempty"`    // Content hash to find the novel after it was moved
+	DetectedRegex string          `json:"detected_regex,omitempty"` // Detected format name: a legacy regex name or heading rule names joined with "+"
+	SplitStrategy string          `json:"split_strategy,omitempty"` // How chapters are split ("regex", "heuristic", "fixed"); empty means "regex"
+	ChunkSize     int             `json:"chunk_size,omitempty"`     // Pseudo-chapter size in runes for the "fixed" strategy
+	Reflow        string          `json:"reflow,omitempty"`         // Reflow mode for hard-wrapped text ("on", "off"); empty means auto
+	Filters       []string        `json:"filters"`                  // Enabled text filters (built-in or FilterRules names); nil means novel.DefaultFilters
+}
+
+// FilterRule is a user-defined regex replacement applied to text before display and speech.
+type FilterRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Replace string `json:"replace,omitempty"` // Empty deletes the matches
 }
 
 // AppConfig holds the application's less frequently changing configuration.
 type AppConfig struct {
+	SchemaVersion   int                   `json:"schema_version"`
 	Novels          map[string]*NovelInfo `json:"novels"` // Map from FilePath to NovelInfo
 	ActiveNovelPath string                `json:"active_novel_path"`
 	AutoReadNext    bool                  `json:"auto_read_next,omitempty"` // Feature: Auto-read next chapter
+	FilterRules     []FilterRule          `json:"filter_rules,omitempty"`   // User regex rules, enabled per novel
+	LibraryRoots    []string              `json:"library_roots,omitempty"`  // Directories searched for moved novels
+	Voice           string                `json:"voice,omitempty"`          // TTS voice; empty uses the system voice
+	Rate            int                   `json:"rate,omitempty"`           // Speaking rate in words per minute; 0 uses the voice's rate
+	Recap           Recap                 `json:"recap,omitzero"`           // Recap read when resuming a novel after a break
+}
+
+// Recap modes.
+const (
+	RecapSegments = "segments" // Re-read the last Amount segments
+	RecapSeconds  = "seconds"  // Re-read about the last Amount seconds of text
+	RecapCue      = "cue"      // Say "Previously" with the chapter title
+)
+
+// Recap configures what 'read' does before continuing a novel that wasn't read for a
+// while, to bring back the context.
+type Recap struct {
+	Mode   string `json:"mode,omitempty"`   // RecapSegments, RecapSeconds or RecapCue; empty turns recaps off
+	Amount int    `json:"amount,omitempty"` // Segments or seconds to re-read
+	After  string `json:"after,omitempty"`  // Break needed for a recap, as a duration such as "24h"; empty means 12h
+	Rate   int    `json:"rate,omitempty"`   // Words per minute of the re-read text; 0 uses the normal rate
 }
 
 // DefaultConfigPath returns the default path for the main configuration file.
 func DefaultConfigPath() (string, error) {
-	configDir, err := os.UserConfigDir()
+	dir, err := DefaultDir()
 	if err != nil {
 		return "", err
 	}
-	appConfigDir := filepath.Join(configDir, "go-novel-reader") // Use new name
-	return filepath.Join(appConfigDir, "config.json"), nil
+	return filepath.Join(dir, "config.json"), nil
 }
 
 // LoadConfig loads the main configuration from the specified path.
+// A corrupt file is restored from the newest valid backup (see readJSONFile).
 func LoadConfig(configPath string) (*AppConfig, error) {
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			cfg := &AppConfig{
-				Novels:       make(map[string]*NovelInfo),
-				AutoReadNext: false,
-			}
-			return cfg, nil
-		}
-		return nil, err
-	}
-
 	var cfg AppConfig
-	err = json.Unmarshal(data, &cfg)
+	err := withLock(configPath, func() error {
+		err := readConfig(configPath, &cfg)
+		if err != nil && !os.IsNotExist(err) && !errors.Is(err, errNoData) {
+			return err
+		}
+		// Ensure Novels map is initialized after loading
+		if cfg.Novels == nil {
+			cfg.Novels = make(map[string]*NovelInfo)
+		}
+		return recordConfig(configPath, &cfg)
+	})
 	if err != nil {
 		return nil, err
 	}
-	// Ensure Novels map is initialized after loading
-	if cfg.Novels == nil {
-		cfg.Novels = make(map[string]*NovelInfo)
-	}
 	return &cfg, nil
 }
 
-// SaveConfig saves the main configuration to the specified path.
+// SaveConfig atomically saves the main configuration to the specified path,
+// keeping the previous version as a backup. Changes other instances saved in the
+// meantime are merged into cfg first: novels and settings this process did not
+// change take their saved value.
 func SaveConfig(configPath string, cfg *AppConfig) error {
-	data, err := json.MarshalIndent(cfg, "", "  ")
-	if err != nil {
+	return withLock(configPath, func() error {
+		// Merge only with readable data, so a lost file doesn't delete every novel.
+		var disk AppConfig
+		err := readConfig(configPath, &disk)
+		if err == nil {
+			err = mergeConfig(configPath, cfg, &disk)
+		}
+		if err != nil && !os.IsNotExist(err) && !errors.Is(err, errNoData) {
+			return err
+		}
+		cfg.SchemaVersion = ConfigSchemaVersion
+		data, err := json.MarshalIndent(cfg, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(configPath, data, 0640); err != nil {
+			return err
+		}
+		return recordConfig(configPath, cfg)
+	})
+}
+
+// readConfig reads the configuration file at path into cfg, upgrading older layouts.
+func readConfig(path string, cfg *AppConfig) error {
+	var raw json.RawMessage
+	if err := readJSONFile(path, &raw); err != nil {
 		return err
 	}
-	dir := filepath.Dir(configPath)
-	if err := os.MkdirAll(dir, 0750); err != nil {
+	data, err := migrateJSON(path, raw, ConfigSchemaVersion, configMigrations)
+	if err != nil {
 		return err
 	}
-	return os.WriteFile(configPath, data, 0640)
+	return json.Unmarshal(data, cfg)
 }
 
 // --- Progress Data ---
 
 // ProgressInfo holds the reading progress for a single novel.
+//
+// The last read chapter and segment are where reading continues: while reading, the
+// segment being spoken; once a segment was heard to its end, the next one. A segment
+// cut off while speaking is therefore read again.
 type ProgressInfo struct {
-	LastReadChapterIndex int `json:"last_read_chapter_index"`
-	LastReadSegmentIndex int `json:"last_read_segment_index"`
+	LastReadChapterIndex int       `json:"last_read_chapter_index"`
+	LastReadSegmentIndex int       `json:"last_read_segment_index"`
+	CompletedChapters    []string  `json:"completed_chapters,omitempty"` // Content hashes of the chapters heard to their last segment
+	LastReadAt           time.Time `json:"last_read_at,omitzero"`        // When a segment was last heard to its end
+}
+
+// NovelStatus says how far a novel has been read.
+type NovelStatus string
+
+const (
+	StatusUnread   NovelStatus = "unread"   // Nothing heard yet
+	StatusReading  NovelStatus = "reading"  // Started but the last chapter not completed
+	StatusFinished NovelStatus = "finished" // The last chapter was heard to its end
+)
+
+// NovelStatuses lists the statuses in reading order.
+var NovelStatuses = []NovelStatus{StatusUnread, StatusReading, StatusFinished}
+
+// Completed reports whether the chapter with the given content hash was heard to its end.
+func (p *ProgressInfo) Completed(hash string) bool {
+	return p != nil && slices.Contains(p.CompletedChapters, hash)
+}
+
+// Complete marks the chapter with the given content hash as heard to its end and
+// reports whether it wasn't already. Chapters are kept by content, so the marks
+// follow them when a novel's file changes, and a rewritten chapter counts as new.
+func (p *ProgressInfo) Complete(hash string) bool {
+	if hash == "" || p.Completed(hash) {
+		return false
+	}
+	p.CompletedChapters = append(p.CompletedChapters, hash)
+	return true
+}
+
+// Status returns the status of the novel whose chapters have the given content
+// hashes. A novel is finished once its last chapter was completed, so chapters added
+// to a finished serial make it one being read again.
+func (p *ProgressInfo) Status(hashes []string) NovelStatus {
+	switch {
+	case p == nil:
+		return StatusUnread
+	case len(hashes) > 0 && p.Completed(hashes[len(hashes)-1]):
+		return StatusFinished
+	case len(p.CompletedChapters) > 0 || p.LastReadChapterIndex != 0 || p.LastReadSegmentIndex != 0:
+		return StatusReading
+	}
+	return StatusUnread
 }
 
 // ProgressData holds the reading progress for all novels.
 type ProgressData map[string]*ProgressInfo // Map from FilePath to ProgressInfo
 
+// progressFile is the layout of progress.json.
+type progressFile struct {
+	SchemaVersion int          `json:"schema_version"`
+	Novels        ProgressData `json:"novels"`
+}
+
 // DefaultProgressPath returns the default path for the progress file.
 func DefaultProgressPath() (string, error) {
-	configDir, err := os.UserConfigDir()
+	dir, err := DefaultDir()
 	if err != nil {
 		return "", err
 	}
-	appConfigDir := filepath.Join(configDir, "go-novel-reader") // Use new name
-	return filepath.Join(appConfigDir, "progress.json"), nil
+	return filepath.Join(dir, "progress.json"), nil
 }
 
 // LoadProgress loads the progress data from the specified path.
-// If the file doesn't exist, it returns an initialized map.
+// If the file doesn't exist, it returns an initialized map. A corrupt file is
+// restored from the newest valid backup with a warning (see readJSONFile).
 func LoadProgress(progressPath string) (ProgressData, error) {
-	data, err := os.ReadFile(progressPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// Return empty initialized map if file doesn't exist
-			return make(ProgressData), nil
-		}
-		return nil, err
-	}
-
 	var progress ProgressData
-	err = json.Unmarshal(data, &progress)
+	err := withLock(progressPath, func() error {
+		err := readProgress(progressPath, &progress)
+		if err != nil && !os.IsNotExist(err) && !errors.Is(err, errNoData) {
+			return err
+		}
+		// Ensure map is not nil after loading
+		if progress == nil {
+			progress = make(ProgressData)
+		}
+		return recordProgress(progressPath, progress)
+	})
 	if err != nil {
-		// If unmarshalling fails, return an empty map for robustness.
-		return make(ProgressData), nil
-	}
-	// Ensure map is not nil after loading
-	if progress == nil {
-		progress = make(ProgressData)
+		return nil, err
 	}
 	return progress, nil
 }
 
-// SaveProgress saves the progress data to the specified path.
+// SaveProgress atomically saves the progress data to the specified path,
+// keeping the previous version as a backup. Progress other instances saved in the
+// meantime is merged in first, novel by novel, as in SaveConfig.
 func SaveProgress(progressPath string, progress ProgressData) error {
-	data, err := json.MarshalIndent(progress, "", "  ")
+	return withLock(progressPath, func() error {
+		var disk ProgressData
+		err := readProgress(progressPath, &disk)
+		if err == nil {
+			err = mergeProgress(progressPath, progress, disk)
+		}
+		if err != nil && !os.IsNotExist(err) && !errors.Is(err, errNoData) {
+			return err
+		}
+		data, err := json.MarshalIndent(progressFile{SchemaVersion: ProgressSchemaVersion, Novels: progress}, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(progressPath, data, 0640); err != nil {
+			return err
+		}
+		return recordProgress(progressPath, progress)
+	})
+}
+
+// readProgress reads the progress file at path into progress, upgrading older layouts.
+func readProgress(path string, progress *ProgressData) error {
+	var raw json.RawMessage
+	if err := readJSONFile(path, &raw); err != nil {
+		return err
+	}
+	data, err := migrateJSON(path, raw, ProgressSchemaVersion, progressMigrations)
 	if err != nil {
 		return err
 	}
-	dir := filepath.Dir(progressPath)
-	if err := os.MkdirAll(dir, 0750); err != nil {
+	var file progressFile
+	if err := json.Unmarshal(data, &file); err != nil {
 		return err
 	}
-	return os.WriteFile(progressPath, data, 0640)
+	*progress = file.Novels
+	return nil
 }
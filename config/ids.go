@@ -0,0 +1,33 @@
+package config
+
+import "math/rand/v2"
+
+// idAlphabet holds the letters used in novel IDs. IDs have no digits, so they can
+// never be mistaken for the list positions used by older versions, and leave out
+// easily confused letters.
+const idAlphabet = "abcdefghjkmnpqrstuvwxyz"
+
+// idLength is the length of a novel ID.
+const idLength = 4
+
+// NewNovelID returns a random novel ID not used by any novel in novels.
+func NewNovelID(novels map[string]*NovelInfo) string {
+	used := make(map[string]bool, len(novels))
+	for _, info := range novels {
+		used[info.ID] = true
+	}
+	return newID(used)
+}
+
+// newID returns a random ID that is not in used.
+func newID(used map[string]bool) string {
+	id := make([]byte, idLength)
+	for {
+		for i := range id {
+			id[i] = idAlphabet[rand.IntN(len(idAlphabet))]
+		}
+		if !used[string(id)] {
+			return string(id)
+		}
+	}
+}
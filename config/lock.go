@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errLocked is returned by lockFile when the lock is held elsewhere and wait is false.
+var errLocked = errors.New("locked")
+
+// withLock runs fn while holding the advisory lock guarding path, so a load-merge-save
+// cycle never interleaves with another process. Loads take it too, since loading may
+// upgrade the file (see migrateJSON).
+func withLock(path string, fn func() error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	f, err := lockFile(path+".lock", true)
+	if err != nil {
+		return fmt.Errorf("locking %s: %w", filepath.Base(path), err)
+	}
+	defer unlockFile(f)
+	return fn()
+}
+
+// ReaderRunningError reports that another process is already reading aloud.
+type ReaderRunningError struct {
+	PID int // Process holding the reader lock, 0 if unknown
+}
+
+func (e *ReaderRunningError) Error() string {
+	if e.PID == 0 {
+		return "another instance is already reading"
+	}
+	return fmt.Sprintf("another instance (PID %d) is already reading", e.PID)
+}
+
+// ReaderLock marks this process as the one reading aloud. It is released when the
+// process exits, even if it crashes.
+type ReaderLock struct {
+	file *os.File
+}
+
+// readerLockPath returns the reader lock file in the data directory.
+func readerLockPath(dir string) string {
+	return filepath.Join(dir, "reader.lock")
+}
+
+// AcquireReaderLock takes the reader lock without waiting. If another process holds
+// it, a *ReaderRunningError naming that process is returned.
+func AcquireReaderLock(dir string) (*ReaderLock, error) {
+	path := readerLockPath(dir)
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, err
+	}
+	f, err := lockFile(path, false)
+	if errors.Is(err, errLocked) {
+		pid := 0
+		if data, err := os.ReadFile(path); err == nil {
+			pid, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+		}
+		return nil, &ReaderRunningError{PID: pid}
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Record our PID so a second instance can offer to take over.
+	if err := f.Truncate(0); err == nil {
+		f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return &ReaderLock{file: f}, nil
+}
+
+// TakeOverReaderLock asks the reading process to exit (it saves its progress first)
+// and takes the reader lock once it is gone, giving up after timeout.
+func TakeOverReaderLock(dir string, pid int, timeout time.Duration) (*ReaderLock, error) {
+	if pid == 0 {
+		return nil, &ReaderRunningError{}
+	}
+	if err := terminate(pid); err != nil {
+		return nil, fmt.Errorf("stopping PID %d: %w", pid, err)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		lock, err := AcquireReaderLock(dir)
+		var running *ReaderRunningError
+		if !errors.As(err, &running) || time.Now().After(deadline) {
+			return lock, err
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// Release gives up the reader lock.
+func (l *ReaderLock) Release() error {
+	return unlockFile(l.file)
+}
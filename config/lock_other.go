@@ -0,0 +1,23 @@
+//go:build !unix
+
+package config
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile opens path without locking: advisory locks are only available on Unix.
+func lockFile(path string, wait bool) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0640)
+}
+
+// unlockFile closes a file opened by lockFile.
+func unlockFile(f *os.File) error {
+	return f.Close()
+}
+
+// terminate is not supported without Unix signals.
+func terminate(pid int) error {
+	return errors.New("stopping another instance is not supported on this platform")
+}
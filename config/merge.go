@@ -0,0 +1,199 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// snapshot is the content of a data file as this process last loaded or saved it. It
+// is the common base of the three-way merge done on save: entries this process did
+// not change since then take their current value on disk, so concurrent instances
+// only overwrite each other where they changed the same entry.
+type snapshot struct {
+	settings map[string]json.RawMessage // Top-level fields other than the novels map
+	entries  map[string]json.RawMessage // Per-novel entries, by file path
+}
+
+var (
+	snapshotsMu sync.Mutex
+	snapshots   = make(map[string]*snapshot) // By data file path
+)
+
+// setSnapshot records the state of a data file after loading or saving it.
+func setSnapshot(path string, settings, entries map[string]json.RawMessage) {
+	snapshotsMu.Lock()
+	defer snapshotsMu.Unlock()
+	snapshots[path] = &snapshot{settings: settings, entries: entries}
+}
+
+// getSnapshot returns the recorded state of a data file; it is empty if the file
+// was never loaded, in which case every in-memory entry counts as changed.
+func getSnapshot(path string) *snapshot {
+	snapshotsMu.Lock()
+	defer snapshotsMu.Unlock()
+	if s, ok := snapshots[path]; ok {
+		return s
+	}
+	return &snapshot{}
+}
+
+// rawEntries encodes every entry of m on its own so entries can be compared.
+func rawEntries[T any](m map[string]*T) (map[string]json.RawMessage, error) {
+	raw := make(map[string]json.RawMessage, len(m))
+	for key, v := range m {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		raw[key] = data
+	}
+	return raw, nil
+}
+
+// mergeRaw merges per key: keys changed (or deleted) in cur since base keep the
+// value from cur, all others take the value from disk.
+func mergeRaw(base, cur, disk map[string]json.RawMessage) map[string]json.RawMessage {
+	merged := make(map[string]json.RawMessage)
+	keys := make(map[string]bool)
+	for _, m := range []map[string]json.RawMessage{base, cur, disk} {
+		for key := range m {
+			keys[key] = true
+		}
+	}
+	for key := range keys {
+		b, inBase := base[key]
+		c, inCur := cur[key]
+		src := disk
+		if inBase != inCur || !bytes.Equal(b, c) {
+			src = cur
+		}
+		if v, ok := src[key]; ok {
+			merged[key] = v
+		}
+	}
+	return merged
+}
+
+// applyEntries updates m to the merged entries. Entries that are kept are updated in
+// place, so pointers held elsewhere stay valid; keep carries over in-memory state
+// that is not saved (may be nil).
+func applyEntries[T any](m map[string]*T, cur, merged map[string]json.RawMessage, keep func(dst, old *T)) error {
+	for key := range m {
+		if _, ok := merged[key]; !ok {
+			delete(m, key)
+		}
+	}
+	for key, data := range merged {
+		if bytes.Equal(cur[key], data) {
+			continue
+		}
+		var v T
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		if old, ok := m[key]; ok {
+			if keep != nil {
+				keep(&v, old)
+			}
+			*old = v
+			continue
+		}
+		m[key] = &v
+	}
+	return nil
+}
+
+// configSettings encodes the top-level fields of cfg other than the novels map.
+func configSettings(cfg *AppConfig) (map[string]json.RawMessage, error) {
+	settings := *cfg
+	settings.Novels = nil
+	data, err := json.Marshal(&settings)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	delete(raw, "novels")
+	return raw, nil
+}
+
+// mergeConfig merges the configuration on disk into cfg, entry by entry and setting
+// by setting, keeping what this process changed since it loaded or last saved path.
+func mergeConfig(path string, cfg, disk *AppConfig) error {
+	base := getSnapshot(path)
+
+	curSettings, err := configSettings(cfg)
+	if err != nil {
+		return err
+	}
+	diskSettings, err := configSettings(disk)
+	if err != nil {
+		return err
+	}
+	settings := mergeRaw(base.settings, curSettings, diskSettings)
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	var merged AppConfig
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return err
+	}
+	merged.Novels = cfg.Novels
+	*cfg = merged
+
+	curEntries, err := rawEntries(cfg.Novels)
+	if err != nil {
+		return err
+	}
+	diskEntries, err := rawEntries(disk.Novels)
+	if err != nil {
+		return err
+	}
+	entries := mergeRaw(base.entries, curEntries, diskEntries)
+	return applyEntries(cfg.Novels, curEntries, entries, func(dst, old *NovelInfo) {
+		dst.Chapters = old.Chapters
+	})
+}
+
+// recordConfig remembers cfg as the state of the configuration file at path.
+func recordConfig(path string, cfg *AppConfig) error {
+	settings, err := configSettings(cfg)
+	if err != nil {
+		return err
+	}
+	entries, err := rawEntries(cfg.Novels)
+	if err != nil {
+		return err
+	}
+	setSnapshot(path, settings, entries)
+	return nil
+}
+
+// mergeProgress merges the progress on disk into progress novel by novel, keeping
+// what this process changed since it loaded or last saved path.
+func mergeProgress(path string, progress, disk ProgressData) error {
+	cur, err := rawEntries(progress)
+	if err != nil {
+		return err
+	}
+	diskEntries, err := rawEntries(disk)
+	if err != nil {
+		return err
+	}
+	merged := mergeRaw(getSnapshot(path).entries, cur, diskEntries)
+	return applyEntries(progress, cur, merged, nil)
+}
+
+// recordProgress remembers progress as the state of the progress file at path.
+func recordProgress(path string, progress ProgressData) error {
+	entries, err := rawEntries(progress)
+	if err != nil {
+		return err
+	}
+	setSnapshot(path, nil, entries)
+	return nil
+}
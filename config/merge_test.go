@@ -0,0 +1,233 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// raw builds entries from plain strings, which are valid JSON once quoted.
+func raw(kv ...string) map[string]json.RawMessage {
+	m := make(map[string]json.RawMessage)
+	for i := 0; i < len(kv); i += 2 {
+		m[kv[i]] = json.RawMessage(`"` + kv[i+1] + `"`)
+	}
+	return m
+}
+
+func TestMergeRaw(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs map[string]json.RawMessage
+		want               map[string]json.RawMessage
+	}{
+		{
+			name: "unchanged",
+			base: raw("a", "1"), ours: raw("a", "1"), theirs: raw("a", "1"),
+			want: raw("a", "1"),
+		},
+		{
+			name: "changed by us",
+			base: raw("a", "1"), ours: raw("a", "2"), theirs: raw("a", "1"),
+			want: raw("a", "2"),
+		},
+		{
+			name: "changed by them",
+			base: raw("a", "1"), ours: raw("a", "1"), theirs: raw("a", "3"),
+			want: raw("a", "3"),
+		},
+		{
+			name: "changed by both, ours wins",
+			base: raw("a", "1"), ours: raw("a", "2"), theirs: raw("a", "3"),
+			want: raw("a", "2"),
+		},
+		{
+			name: "different entries changed by each",
+			base: raw("a", "1", "b", "1"), ours: raw("a", "2", "b", "1"), theirs: raw("a", "1", "b", "3"),
+			want: raw("a", "2", "b", "3"),
+		},
+		{
+			name: "deleted by us",
+			base: raw("a", "1", "b", "1"), ours: raw("b", "1"), theirs: raw("a", "1", "b", "1"),
+			want: raw("b", "1"),
+		},
+		{
+			name: "deleted by them",
+			base: raw("a", "1", "b", "1"), ours: raw("a", "1", "b", "1"), theirs: raw("b", "1"),
+			want: raw("b", "1"),
+		},
+		{
+			name: "deleted by us, changed by them",
+			base: raw("a", "1"), ours: raw(), theirs: raw("a", "3"),
+			want: raw(),
+		},
+		{
+			name: "deleted by them, changed by us",
+			base: raw("a", "1"), ours: raw("a", "2"), theirs: raw(),
+			want: raw("a", "2"),
+		},
+		{
+			name: "added by each: union",
+			base: raw(), ours: raw("a", "1"), theirs: raw("b", "1"),
+			want: raw("a", "1", "b", "1"),
+		},
+		{
+			name: "added by both, ours wins",
+			base: raw(), ours: raw("a", "1"), theirs: raw("a", "2"),
+			want: raw("a", "1"),
+		},
+		{
+			name: "never loaded: everything in memory counts as changed",
+			base: nil, ours: raw("a", "1"), theirs: raw("a", "2", "b", "2"),
+			want: raw("a", "1", "b", "2"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeRaw(tt.base, tt.ours, tt.theirs)
+			if !maps.EqualFunc(got, tt.want, func(a, b json.RawMessage) bool { return string(a) == string(b) }) {
+				t.Errorf("mergeRaw() = %s, want %s", dump(got), dump(tt.want))
+			}
+		})
+	}
+}
+
+func dump(m map[string]json.RawMessage) string {
+	data, _ := json.Marshal(m)
+	return string(data)
+}
+
+// writeTheirs saves v to path as another process would, without touching the
+// snapshot this process merges against.
+func writeTheirs(t *testing.T, path string, v any) {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0640); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaveProgressMerges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.json")
+	writeTheirs(t, path, progressFile{SchemaVersion: ProgressSchemaVersion, Novels: ProgressData{
+		"both":      {LastReadChapterIndex: 1},
+		"ours":      {LastReadChapterIndex: 1},
+		"theirs":    {LastReadChapterIndex: 1},
+		"deleted":   {LastReadChapterIndex: 1},
+		"untouched": {LastReadChapterIndex: 1},
+	}})
+	ours, err := LoadProgress(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTheirs(t, path, progressFile{SchemaVersion: ProgressSchemaVersion, Novels: ProgressData{
+		"both":      {LastReadChapterIndex: 3},
+		"ours":      {LastReadChapterIndex: 1},
+		"theirs":    {LastReadChapterIndex: 3},
+		"untouched": {LastReadChapterIndex: 1},
+		"added":     {LastReadChapterIndex: 3},
+	}})
+	ours["both"].LastReadChapterIndex = 2
+	ours["ours"].LastReadChapterIndex = 2
+	ours["mine"] = &ProgressInfo{LastReadChapterIndex: 2}
+	kept := ours["theirs"] // Updated in place by the merge
+	if err := SaveProgress(path, ours); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]int{"both": 2, "ours": 2, "theirs": 3, "untouched": 1, "added": 3, "mine": 2}
+	saved, err := LoadProgress(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]ProgressData{"in memory": ours, "saved": saved} {
+		got := make(map[string]int)
+		for key, info := range data {
+			got[key] = info.LastReadChapterIndex
+		}
+		if !maps.Equal(got, want) {
+			t.Errorf("%s progress = %v, want %v", name, got, want)
+		}
+	}
+	if kept != ours["theirs"] {
+		t.Error("merged entry was replaced instead of updated in place")
+	}
+}
+
+func TestSaveConfigMerges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	writeTheirs(t, path, AppConfig{SchemaVersion: ConfigSchemaVersion, Novels: map[string]*NovelInfo{
+		"/a.txt": {ID: "aaaa", Title: "A", FilePath: "/a.txt"},
+		"/b.txt": {ID: "bbbb", Title: "B", FilePath: "/b.txt"},
+	}, Voice: "Alex", Rate: 180})
+	ours, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTheirs(t, path, AppConfig{SchemaVersion: ConfigSchemaVersion, Novels: map[string]*NovelInfo{
+		"/a.txt": {ID: "aaaa", Title: "A", FilePath: "/a.txt"},
+		"/b.txt": {ID: "bbbb", Title: "B (renamed)", FilePath: "/b.txt"},
+		"/c.txt": {ID: "cccc", Title: "C", FilePath: "/c.txt"},
+	}, Voice: "Tingting", Rate: 180, ActiveNovelPath: "/c.txt"})
+	ours.Novels["/d.txt"] = &NovelInfo{ID: "dddd", Title: "D", FilePath: "/d.txt"}
+	delete(ours.Novels, "/a.txt")
+	ours.Rate = 220
+	if err := SaveConfig(path, ours); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, cfg := range map[string]*AppConfig{"in memory": ours, "saved": saved} {
+		if got, want := slices.Sorted(maps.Keys(cfg.Novels)), []string{"/b.txt", "/c.txt", "/d.txt"}; !slices.Equal(got, want) {
+			t.Errorf("%s novels = %v, want %v", name, got, want)
+		}
+		if got := cfg.Novels["/b.txt"].Title; got != "B (renamed)" {
+			t.Errorf("%s title of /b.txt = %q, want their rename", name, got)
+		}
+		if cfg.Rate != 220 || cfg.Voice != "Tingting" || cfg.ActiveNovelPath != "/c.txt" {
+			t.Errorf("%s settings = rate %d, voice %q, active %q; want our rate and their voice and active novel",
+				name, cfg.Rate, cfg.Voice, cfg.ActiveNovelPath)
+		}
+	}
+}
+
+func TestBookmarksFromTwoInstances(t *testing.T) {
+	dir := t.TempDir()
+	first, second := newJSONStorage(dir), newJSONStorage(dir)
+	if err := first.AddBookmark(Bookmark{NovelID: "aaaa", Name: "duel"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.AddBookmark(Bookmark{NovelID: "aaaa", Name: "ending"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.AddBookmark(Bookmark{NovelID: "bbbb", Name: "duel"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.AddBookmark(Bookmark{NovelID: "aaaa", Name: "ending"}); !errors.Is(err, ErrExists) {
+		t.Errorf("adding a bookmark name twice: err = %v, want ErrExists", err)
+	}
+
+	bookmarks, err := first.Bookmarks("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, b := range bookmarks {
+		got = append(got, b.NovelID+"/"+b.Name)
+	}
+	if want := []string{"aaaa/duel", "aaaa/ending", "bbbb/duel"}; !slices.Equal(got, want) {
+		t.Errorf("bookmarks = %v, want %v", got, want)
+	}
+}
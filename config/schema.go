@@ -0,0 +1,156 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/xqbumu/go-novel-reader/novel"
+)
+
+// Schema versions of the data files. Files written before versioning (the v8
+// layout) have no schema_version field and count as version 1.
+const (
+	ConfigSchemaVersion   = 3 // Adds novel IDs and titles
+	ProgressSchemaVersion = 2 // Wraps the novels map: {"schema_version": 2, "novels": {...}}
+)
+
+// migration upgrades a decoded file by one schema version. Setting the new
+// schema_version is left to migrateJSON.
+type migration func(doc map[string]json.RawMessage) (map[string]json.RawMessage, error)
+
+// configMigrations[v] upgrades config.json from version v to v+1.
+var configMigrations = map[int]migration{
+	1: func(doc map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+		return doc, nil // Only schema_version is added
+	},
+	2: func(doc map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+		// Every novel gets a stable ID and a title.
+		var novels map[string]map[string]json.RawMessage
+		if raw, ok := doc["novels"]; ok {
+			if err := json.Unmarshal(raw, &novels); err != nil {
+				return nil, err
+			}
+		}
+		used := make(map[string]bool)
+		for _, entry := range novels {
+			if entry == nil {
+				continue
+			}
+			var src novel.Source
+			json.Unmarshal(entry["file_path"], &src.Path)
+			id := newID(used)
+			used[id] = true
+			entry["id"], _ = json.Marshal(id)
+			entry["title"], _ = json.Marshal(src.Title())
+		}
+		raw, err := json.Marshal(novels)
+		if err != nil {
+			return nil, err
+		}
+		doc["novels"] = raw
+		return doc, nil
+	},
+}
+
+// progressMigrations[v] upgrades progress.json from version v to v+1.
+var progressMigrations = map[int]migration{
+	1: func(doc map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+		// Version 1 is the bare map of novel paths to progress.
+		novels, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]json.RawMessage{"novels": novels}, nil
+	},
+}
+
+// NewerVersionError reports a data file written by a newer version of the program.
+// It is neither loaded nor overwritten, so no data from the newer layout is lost.
+type NewerVersionError struct {
+	Path      string
+	Version   int // Version found in the file
+	Supported int // Newest version this program understands
+}
+
+func (e *NewerVersionError) Error() string {
+	return fmt.Sprintf("%s has schema version %d, but this version of go-novel-reader only supports up to %d; please upgrade",
+		e.Path, e.Version, e.Supported)
+}
+
+// schemaVersion returns the schema_version of a decoded file, 1 if it has none.
+func schemaVersion(doc map[string]json.RawMessage) (int, error) {
+	raw, ok := doc["schema_version"]
+	if !ok {
+		return 1, nil
+	}
+	var version int
+	if err := json.Unmarshal(raw, &version); err != nil {
+		return 0, fmt.Errorf("invalid schema_version: %w", err)
+	}
+	return version, nil
+}
+
+// migrateJSON upgrades the content of the data file at path to version current,
+// one step at a time. Before the first step the original file is copied to the
+// backup directory (once per version), so an upgrade can always be undone by hand;
+// afterwards the upgraded content replaces the file, so the upgrade runs only once
+// (migrations may assign random IDs). The caller must hold the file's lock exclusively.
+func migrateJSON(path string, data []byte, current int, migrations map[int]migration) ([]byte, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		doc = make(map[string]json.RawMessage) // A file holding just null
+	}
+	version, err := schemaVersion(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if version > current {
+		return nil, &NewerVersionError{Path: path, Version: version, Supported: current}
+	}
+	if version == current {
+		return data, nil
+	}
+
+	if err := backupOriginal(path, version); err != nil {
+		return nil, fmt.Errorf("backing up %s before upgrading it: %w", path, err)
+	}
+	for ; version < current; version++ {
+		step, ok := migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("%s: no migration from schema version %d", path, version)
+		}
+		if doc, err = step(doc); err != nil {
+			return nil, fmt.Errorf("%s: migrating from schema version %d: %w", path, version, err)
+		}
+		doc["schema_version"], _ = json.Marshal(version + 1)
+	}
+	data, err = json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(path, data, 0640); err != nil {
+		return nil, fmt.Errorf("saving upgraded %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// backupOriginal keeps a copy of the data file at path as it was before being
+// upgraded from version. These copies are not rotated like regular backups.
+func backupOriginal(path string, version int) error {
+	if _, err := os.Stat(path); err != nil {
+		return nil // Nothing on disk (e.g. restored from a regular backup)
+	}
+	backup := filepath.Join(backupDir(path), fmt.Sprintf("pre-migration-%s.v%d", filepath.Base(path), version))
+	if _, err := os.Stat(backup); err == nil {
+		return nil // Kept on an earlier run
+	}
+	if err := os.MkdirAll(backupDir(path), 0750); err != nil {
+		return err
+	}
+	return copyFile(path, backup)
+}
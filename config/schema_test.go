@@ -0,0 +1,228 @@
+package config
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Data files as older versions of the program wrote them.
+const (
+	// Before versioning: no schema_version, novels without IDs or titles.
+	configV1 = `{
+  "novels": {
+    "/novels/三体.txt": {
+      "file_path": "/novels/三体.txt",
+      "chapter_titles": ["第一章", "第二章"],
+      "detected_regex": "chinese_numeral"
+    },
+    "/novels/dune.md": {
+      "file_path": "/novels/dune.md",
+      "chapter_titles": ["Book One"],
+      "filters": ["markdown"]
+    }
+  },
+  "active_novel_path": "/novels/dune.md",
+  "auto_read_next": true
+}`
+	// Versioned, but from before novel IDs.
+	configV2 = `{
+  "schema_version": 2,
+  "novels": {
+    "/novels/三体.txt": {"file_path": "/novels/三体.txt", "chapter_titles": ["第一章", "第二章"]},
+    "/novels/dune.md": {"file_path": "/novels/dune.md", "chapter_titles": ["Book One"]}
+  },
+  "active_novel_path": "/novels/dune.md",
+  "auto_read_next": true
+}`
+	// Before versioning: the bare map of novel paths to progress.
+	progressV1 = `{
+  "/novels/三体.txt": {"last_read_chapter_index": 1, "last_read_segment_index": 7},
+  "/novels/dune.md": {"last_read_chapter_index": 0, "last_read_segment_index": 2}
+}`
+)
+
+// writeFixture writes content to name in a new directory and returns its path.
+func writeFixture(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0640); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// checkUpgraded checks that the file at path was upgraded on disk, that loading it
+// again changes nothing, and that the original was kept as a pre-migration backup.
+func checkUpgraded(t *testing.T, path, original string, fromVersion int, load func() error) {
+	t.Helper()
+	upgraded, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := load(); err != nil {
+		t.Fatalf("loading the upgraded file: %v", err)
+	}
+	again, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(upgraded, again) {
+		t.Errorf("loading the upgraded file changed it:\n%s\nto\n%s", upgraded, again)
+	}
+
+	backup := filepath.Join(backupDir(path), fmt.Sprintf("pre-migration-%s.v%d", filepath.Base(path), fromVersion))
+	kept, err := os.ReadFile(backup)
+	if err != nil {
+		t.Fatalf("no pre-migration backup: %v", err)
+	}
+	if string(kept) != original {
+		t.Errorf("pre-migration backup = %s, want the original file", kept)
+	}
+}
+
+func TestMigrateConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		version int
+	}{
+		{"unversioned", configV1, 1},
+		{"version 2", configV2, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFixture(t, "config.json", tt.content)
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.SchemaVersion != ConfigSchemaVersion {
+				t.Errorf("schema version = %d, want %d", cfg.SchemaVersion, ConfigSchemaVersion)
+			}
+			if cfg.ActiveNovelPath != "/novels/dune.md" || !cfg.AutoReadNext {
+				t.Errorf("settings not kept: active %q, auto_next %t", cfg.ActiveNovelPath, cfg.AutoReadNext)
+			}
+			titles := map[string]string{"/novels/三体.txt": "三体", "/novels/dune.md": "dune"}
+			ids := make(map[string]bool)
+			for path, title := range titles {
+				info := cfg.Novels[path]
+				if info == nil {
+					t.Fatalf("novel %s lost", path)
+				}
+				if info.Title != title {
+					t.Errorf("title of %s = %q, want %q", path, info.Title, title)
+				}
+				if len(info.ID) != idLength || ids[info.ID] {
+					t.Errorf("ID of %s = %q, want a new unique ID", path, info.ID)
+				}
+				ids[info.ID] = true
+				if info.FilePath != path || len(info.ChapterTitles) == 0 {
+					t.Errorf("novel %s not kept: %+v", path, info)
+				}
+			}
+
+			checkUpgraded(t, path, tt.content, tt.version, func() error {
+				again, err := LoadConfig(path)
+				if err == nil && again.Novels["/novels/dune.md"].ID != cfg.Novels["/novels/dune.md"].ID {
+					t.Error("IDs changed when loading the upgraded file")
+				}
+				return err
+			})
+		})
+	}
+}
+
+func TestMigrateProgress(t *testing.T) {
+	path := writeFixture(t, "progress.json", progressV1)
+	progress, err := LoadProgress(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][2]int{"/novels/三体.txt": {1, 7}, "/novels/dune.md": {0, 2}}
+	if len(progress) != len(want) {
+		t.Errorf("progress has %d novels, want %d", len(progress), len(want))
+	}
+	for path, pos := range want {
+		info := progress[path]
+		if info == nil || info.LastReadChapterIndex != pos[0] || info.LastReadSegmentIndex != pos[1] {
+			t.Errorf("progress of %s = %+v, want chapter %d, segment %d", path, info, pos[0], pos[1])
+		}
+	}
+	checkUpgraded(t, path, progressV1, 1, func() error {
+		_, err := LoadProgress(path)
+		return err
+	})
+}
+
+func TestMigrateCurrentIsUnchanged(t *testing.T) {
+	path := writeFixture(t, "config.json", configV1)
+	if _, err := LoadConfig(path); err != nil {
+		t.Fatal(err)
+	}
+	current, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := migrateJSON(path, current, ConfigSchemaVersion, configMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, current) {
+		t.Errorf("migrating current data changed it:\n%s\nto\n%s", current, data)
+	}
+}
+
+func TestMigrateNewerVersion(t *testing.T) {
+	content := `{"schema_version": 99, "novels": {}}`
+	path := writeFixture(t, "config.json", content)
+	_, err := LoadConfig(path)
+	var newer *NewerVersionError
+	if !errors.As(err, &newer) || newer.Version != 99 {
+		t.Fatalf("LoadConfig() error = %v, want a NewerVersionError for version 99", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != content {
+		t.Errorf("file from a newer version was changed to %s", data)
+	}
+}
+
+func TestOpenSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "library.db")
+	for range 2 { // Opening an existing database again keeps its data
+		s, err := openSQLite(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.AddBookmark(Bookmark{NovelID: "aaaa", Name: "duel", Chapter: 3, Segment: 12, Snippet: "Swords crossed.", CreatedAt: time.Now()}); err != nil && !errors.Is(err, ErrExists) {
+			t.Fatalf("adding a bookmark: %v", err)
+		}
+		bookmarks, err := s.Bookmarks("aaaa")
+		s.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bookmarks) != 1 || bookmarks[0].Segment != 12 || bookmarks[0].Snippet != "Swords crossed." {
+			t.Errorf("bookmarks = %+v, want the one added", bookmarks)
+		}
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(fmt.Sprintf("PRAGMA user_version = %d", sqliteSchemaVersion+1))
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var newer *NewerVersionError
+	if _, err := openSQLite(path); !errors.As(err, &newer) {
+		t.Errorf("opening a database of a newer version: err = %v, want a NewerVersionError", err)
+	}
+}
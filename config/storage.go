@@ -0,0 +1,206 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Storage backends.
+const (
+	BackendJSON   = "json"   // config.json, progress.json, bookmarks.json and history.jsonl
+	BackendSQLite = "sqlite" // library.db
+)
+
+// ErrNotFound is returned when a bookmark to remove does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrExists is returned when adding a bookmark whose name is already used.
+var ErrExists = errors.New("already exists")
+
+// Bookmark marks a position in a novel.
+type Bookmark struct {
+	NovelID   string    `json:"novel_id"`
+	Name      string    `json:"name"`
+	Chapter   int       `json:"chapter"` // 0-based chapter index
+	Segment   int       `json:"segment"` // 0-based segment index within the chapter
+	Snippet   string    `json:"snippet,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Session records one stretch of reading aloud.
+type Session struct {
+	NovelID      string    `json:"novel_id"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	StartChapter int       `json:"start_chapter"` // 0-based position where reading started
+	StartSegment int       `json:"start_segment"`
+	EndChapter   int       `json:"end_chapter"` // 0-based position of the last segment read
+	EndSegment   int       `json:"end_segment"`
+	Segments     int       `json:"segments"` // Segments spoken
+	Chars        int       `json:"chars"`    // Characters spoken
+	Backend      string    `json:"backend,omitempty"`
+	Voice        string    `json:"voice,omitempty"`
+}
+
+// Storage persists the library, settings, progress, bookmarks and reading history.
+// Saves merge with changes other instances made in the meantime, entry by entry.
+type Storage interface {
+	Backend() string  // BackendJSON or BackendSQLite
+	Location() string // File or directory the data is kept in, for messages
+
+	LoadConfig() (*AppConfig, error)
+	SaveConfig(cfg *AppConfig) error
+	LoadProgress() (ProgressData, error)
+	SaveProgress(progress ProgressData) error
+
+	Bookmarks(novelID string) ([]Bookmark, error) // All novels' bookmarks if novelID is empty
+	AddBookmark(b Bookmark) error                 // ErrExists if the novel has a bookmark with that name
+	RemoveBookmark(novelID, name string) error    // ErrNotFound if there is no such bookmark
+
+	AddSession(s Session) error
+	Sessions(since time.Time) ([]Session, error) // Sessions started at or after since, oldest first
+
+	Close() error
+}
+
+// DefaultDir returns the directory holding the application's data.
+func DefaultDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "go-novel-reader"), nil
+}
+
+// sqlitePath returns the SQLite database path in dir.
+func sqlitePath(dir string) string {
+	return filepath.Join(dir, "library.db")
+}
+
+// OpenStorage opens the data in dir: the SQLite database if library.db exists,
+// otherwise the JSON files.
+func OpenStorage(dir string) (Storage, error) {
+	if _, err := os.Stat(sqlitePath(dir)); err == nil {
+		return openSQLite(sqlitePath(dir))
+	}
+	return newJSONStorage(dir), nil
+}
+
+// MigrateStorage moves all data in dir to the backend to. The files of the previous
+// backend are moved to the backup directory; their data is copied in full first, so
+// a failed migration leaves them untouched. It returns the new storage.
+func MigrateStorage(dir, to string) (Storage, error) {
+	from, err := OpenStorage(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer from.Close()
+	if from.Backend() == to {
+		return nil, fmt.Errorf("data is already stored in %s", from.Location())
+	}
+
+	var target Storage
+	var oldFiles []string
+	switch to {
+	case BackendSQLite:
+		target, err = openSQLite(sqlitePath(dir) + ".tmp")
+		oldFiles = newJSONStorage(dir).files()
+	case BackendJSON:
+		// Leftovers of an earlier JSON backend would be merged into; set them aside.
+		if err := moveToBackups(newJSONStorage(dir).files()); err != nil {
+			return nil, err
+		}
+		target = newJSONStorage(dir)
+		oldFiles = []string{sqlitePath(dir), sqlitePath(dir) + "-wal", sqlitePath(dir) + "-shm"}
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q (available: %s, %s)", to, BackendJSON, BackendSQLite)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := copyStorage(from, target); err != nil {
+		target.Close()
+		if to == BackendSQLite {
+			os.Remove(sqlitePath(dir) + ".tmp")
+		}
+		return nil, err
+	}
+	if to == BackendSQLite {
+		// The database is complete; putting it in place switches the backend.
+		if err := target.Close(); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(sqlitePath(dir)+".tmp", sqlitePath(dir)); err != nil {
+			return nil, err
+		}
+		if target, err = openSQLite(sqlitePath(dir)); err != nil {
+			return nil, err
+		}
+	}
+	from.Close()
+	if err := moveToBackups(oldFiles); err != nil {
+		target.Close()
+		return nil, err
+	}
+	return target, nil
+}
+
+// copyStorage copies every kind of data from one storage to another.
+func copyStorage(from, to Storage) error {
+	cfg, err := from.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if err := to.SaveConfig(cfg); err != nil {
+		return err
+	}
+	progress, err := from.LoadProgress()
+	if err != nil {
+		return err
+	}
+	if err := to.SaveProgress(progress); err != nil {
+		return err
+	}
+	bookmarks, err := from.Bookmarks("")
+	if err != nil {
+		return err
+	}
+	for _, b := range bookmarks {
+		if err := to.AddBookmark(b); err != nil {
+			return err
+		}
+	}
+	sessions, err := from.Sessions(time.Time{})
+	if err != nil {
+		return err
+	}
+	for _, s := range sessions {
+		if err := to.AddSession(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moveToBackups moves the existing ones of files into the backup directory, named
+// "migrated-<name>.<time>" so they are never taken for regular backups.
+func moveToBackups(files []string) error {
+	stamp := time.Now().Format(backupTimeFormat)
+	for _, f := range files {
+		if _, err := os.Stat(f); err != nil {
+			continue
+		}
+		if err := os.MkdirAll(backupDir(f), 0750); err != nil {
+			return err
+		}
+		if err := os.Rename(f, filepath.Join(backupDir(f), "migrated-"+filepath.Base(f)+"."+stamp)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
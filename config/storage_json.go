@@ -0,0 +1,178 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// bookmarksSchemaVersion is the schema version of bookmarks.json.
+const bookmarksSchemaVersion = 1
+
+// jsonStorage keeps data in JSON files: config.json and progress.json (rewritten
+// atomically on save), bookmarks.json, and history.jsonl with one session per line.
+type jsonStorage struct {
+	dir string
+}
+
+func newJSONStorage(dir string) *jsonStorage {
+	return &jsonStorage{dir: dir}
+}
+
+func (s *jsonStorage) configPath() string    { return filepath.Join(s.dir, "config.json") }
+func (s *jsonStorage) progressPath() string  { return filepath.Join(s.dir, "progress.json") }
+func (s *jsonStorage) bookmarksPath() string { return filepath.Join(s.dir, "bookmarks.json") }
+func (s *jsonStorage) historyPath() string   { return filepath.Join(s.dir, "history.jsonl") }
+
+// files returns the data files of the JSON backend.
+func (s *jsonStorage) files() []string {
+	return []string{s.configPath(), s.progressPath(), s.bookmarksPath(), s.historyPath()}
+}
+
+func (s *jsonStorage) Backend() string  { return BackendJSON }
+func (s *jsonStorage) Location() string { return s.dir }
+func (s *jsonStorage) Close() error     { return nil }
+
+func (s *jsonStorage) LoadConfig() (*AppConfig, error) { return LoadConfig(s.configPath()) }
+func (s *jsonStorage) SaveConfig(cfg *AppConfig) error { return SaveConfig(s.configPath(), cfg) }
+func (s *jsonStorage) LoadProgress() (ProgressData, error) {
+	return LoadProgress(s.progressPath())
+}
+func (s *jsonStorage) SaveProgress(progress ProgressData) error {
+	return SaveProgress(s.progressPath(), progress)
+}
+
+// bookmarksFile is the layout of bookmarks.json.
+type bookmarksFile struct {
+	SchemaVersion int        `json:"schema_version"`
+	Bookmarks     []Bookmark `json:"bookmarks"`
+}
+
+// readBookmarks reads all bookmarks; the caller holds the file's lock.
+func (s *jsonStorage) readBookmarks() ([]Bookmark, error) {
+	var raw json.RawMessage
+	err := readJSONFile(s.bookmarksPath(), &raw)
+	if os.IsNotExist(err) || errors.Is(err, errNoData) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err := migrateJSON(s.bookmarksPath(), raw, bookmarksSchemaVersion, nil)
+	if err != nil {
+		return nil, err
+	}
+	var file bookmarksFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	return file.Bookmarks, nil
+}
+
+// updateBookmarks applies update to the bookmarks on disk and saves the result.
+func (s *jsonStorage) updateBookmarks(update func([]Bookmark) ([]Bookmark, error)) error {
+	return withLock(s.bookmarksPath(), func() error {
+		bookmarks, err := s.readBookmarks()
+		if err != nil {
+			return err
+		}
+		if bookmarks, err = update(bookmarks); err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(bookmarksFile{SchemaVersion: bookmarksSchemaVersion, Bookmarks: bookmarks}, "", "  ")
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(s.bookmarksPath(), data, 0640)
+	})
+}
+
+func (s *jsonStorage) Bookmarks(novelID string) ([]Bookmark, error) {
+	var found []Bookmark
+	err := withLock(s.bookmarksPath(), func() error {
+		bookmarks, err := s.readBookmarks()
+		for _, b := range bookmarks {
+			if novelID == "" || b.NovelID == novelID {
+				found = append(found, b)
+			}
+		}
+		return err
+	})
+	return found, err
+}
+
+func (s *jsonStorage) AddBookmark(b Bookmark) error {
+	return s.updateBookmarks(func(bookmarks []Bookmark) ([]Bookmark, error) {
+		for _, existing := range bookmarks {
+			if existing.NovelID == b.NovelID && existing.Name == b.Name {
+				return nil, ErrExists
+			}
+		}
+		return append(bookmarks, b), nil
+	})
+}
+
+func (s *jsonStorage) RemoveBookmark(novelID, name string) error {
+	return s.updateBookmarks(func(bookmarks []Bookmark) ([]Bookmark, error) {
+		for i, b := range bookmarks {
+			if b.NovelID == novelID && b.Name == name {
+				return append(bookmarks[:i], bookmarks[i+1:]...), nil
+			}
+		}
+		return nil, ErrNotFound
+	})
+}
+
+// AddSession appends the session to history.jsonl. Appending a line never rewrites
+// earlier history, so the file is not kept atomically like the others.
+func (s *jsonStorage) AddSession(session Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return withLock(s.historyPath(), func() error {
+		f, err := os.OpenFile(s.historyPath(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(append(data, '\n')); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
+
+func (s *jsonStorage) Sessions(since time.Time) ([]Session, error) {
+	var sessions []Session
+	err := withLock(s.historyPath(), func() error {
+		f, err := os.Open(s.historyPath())
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var session Session
+			// A line cut short by a crash is skipped rather than failing all history.
+			if json.Unmarshal(scanner.Bytes(), &session) != nil {
+				continue
+			}
+			if !session.Start.Before(since) {
+				sessions = append(sessions, session)
+			}
+		}
+		return scanner.Err()
+	})
+	return sessions, err
+}
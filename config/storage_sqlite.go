@@ -0,0 +1,372 @@
+package config
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite" // Pure Go SQLite driver, no cgo needed
+)
+
+// sqliteSchemaVersion is the schema version of library.db, kept in PRAGMA user_version.
+const sqliteSchemaVersion = 1
+
+// sqliteMigrations[v] upgrades a database from schema version v to v+1.
+var sqliteMigrations = map[int]string{}
+
+// sqliteSchema creates the tables of library.db. Settings, novels and progress hold
+// one JSON value per setting or novel, so they follow the structs without schema
+// changes; bookmarks and sessions have columns so history can be queried.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS settings (key TEXT PRIMARY KEY, value TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS novels (path TEXT PRIMARY KEY, data TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS progress (path TEXT PRIMARY KEY, data TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS bookmarks (
+	novel_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	chapter INTEGER NOT NULL,
+	segment INTEGER NOT NULL,
+	snippet TEXT NOT NULL,
+	note TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	PRIMARY KEY (novel_id, name)
+);
+CREATE TABLE IF NOT EXISTS sessions (
+	id INTEGER PRIMARY KEY,
+	novel_id TEXT NOT NULL,
+	start_time TEXT NOT NULL,
+	end_time TEXT NOT NULL,
+	start_chapter INTEGER NOT NULL,
+	start_segment INTEGER NOT NULL,
+	end_chapter INTEGER NOT NULL,
+	end_segment INTEGER NOT NULL,
+	segments INTEGER NOT NULL,
+	chars INTEGER NOT NULL,
+	backend TEXT NOT NULL,
+	voice TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS sessions_start_time ON sessions (start_time);
+`
+
+// sqliteTimeFormat stores times in UTC with fixed width, so they sort as text.
+const sqliteTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// sqliteStorage keeps data in a SQLite database. Every save runs in one transaction,
+// and only the rows that changed are written.
+type sqliteStorage struct {
+	path string
+	db   *sql.DB
+}
+
+// openSQLite opens (creating if needed) the database at path.
+func openSQLite(path string) (*sqliteStorage, error) {
+	// Immediate transactions take the write lock up front, so the read-merge-write
+	// in a save can't be interleaved with another instance's; writers wait up to 5s.
+	db, err := sql.Open("sqlite", "file:"+path+"?_txlock=immediate&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	s := &sqliteStorage{path: path, db: db}
+	if err := s.init(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// init creates the schema or upgrades it, refusing databases written by a newer version.
+func (s *sqliteStorage) init() error {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > sqliteSchemaVersion {
+		return &NewerVersionError{Path: s.path, Version: version, Supported: sqliteSchemaVersion}
+	}
+	return s.inTx(func(tx *sql.Tx) error {
+		for ; version > 0 && version < sqliteSchemaVersion; version++ { // 0 is a new database
+			if _, err := tx.Exec(sqliteMigrations[version]); err != nil {
+				return fmt.Errorf("migrating from schema version %d: %w", version, err)
+			}
+		}
+		if _, err := tx.Exec(sqliteSchema); err != nil {
+			return err
+		}
+		_, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", sqliteSchemaVersion))
+		return err
+	})
+}
+
+// inTx runs fn in a transaction, committing if it succeeds.
+func (s *sqliteStorage) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStorage) Backend() string  { return BackendSQLite }
+func (s *sqliteStorage) Location() string { return s.path }
+func (s *sqliteStorage) Close() error     { return s.db.Close() }
+
+// snapshotKey names the merge base of one kind of data in this database.
+func (s *sqliteStorage) snapshotKey(kind string) string {
+	return s.path + "#" + kind
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx.
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// readRows reads a key/JSON value table into a map.
+func readRows(q queryer, query string) (map[string]json.RawMessage, error) {
+	rows, err := q.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	values := make(map[string]json.RawMessage)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		values[key] = json.RawMessage(value)
+	}
+	return values, rows.Err()
+}
+
+// writeRows brings a key/JSON value table from old to new, touching changed rows only.
+func writeRows(tx *sql.Tx, table, keyCol, valueCol string, old, new map[string]json.RawMessage) error {
+	for key, value := range new {
+		if prev, ok := old[key]; ok && bytes.Equal(prev, value) {
+			continue
+		}
+		_, err := tx.Exec(fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES (?, ?) ON CONFLICT (%s) DO UPDATE SET %s = excluded.%s",
+			table, keyCol, valueCol, keyCol, valueCol, valueCol), key, string(value))
+		if err != nil {
+			return err
+		}
+	}
+	for key := range old {
+		if _, ok := new[key]; !ok {
+			if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", table, keyCol), key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadConfig reads settings and novels into an AppConfig.
+func loadConfig(q queryer) (*AppConfig, error) {
+	settings, err := readRows(q, "SELECT key, value FROM settings")
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	var cfg AppConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	novels, err := readRows(q, "SELECT path, data FROM novels")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Novels = make(map[string]*NovelInfo, len(novels))
+	for path, data := range novels {
+		var info NovelInfo
+		if err := json.Unmarshal(data, &info); err != nil {
+			return nil, fmt.Errorf("novel %s: %w", path, err)
+		}
+		cfg.Novels[path] = &info
+	}
+	return &cfg, nil
+}
+
+// loadProgress reads the progress table.
+func loadProgress(q queryer) (ProgressData, error) {
+	rows, err := readRows(q, "SELECT path, data FROM progress")
+	if err != nil {
+		return nil, err
+	}
+	progress := make(ProgressData, len(rows))
+	for path, data := range rows {
+		var info ProgressInfo
+		if err := json.Unmarshal(data, &info); err != nil {
+			return nil, fmt.Errorf("progress of %s: %w", path, err)
+		}
+		progress[path] = &info
+	}
+	return progress, nil
+}
+
+func (s *sqliteStorage) LoadConfig() (*AppConfig, error) {
+	cfg, err := loadConfig(s.db)
+	if err != nil {
+		return nil, err
+	}
+	return cfg, recordConfig(s.snapshotKey("config"), cfg)
+}
+
+func (s *sqliteStorage) SaveConfig(cfg *AppConfig) error {
+	cfg.SchemaVersion = ConfigSchemaVersion
+	err := s.inTx(func(tx *sql.Tx) error {
+		disk, err := loadConfig(tx)
+		if err != nil {
+			return err
+		}
+		oldSettings, err := configSettings(disk)
+		if err != nil {
+			return err
+		}
+		oldNovels, err := rawEntries(disk.Novels)
+		if err != nil {
+			return err
+		}
+		if err := mergeConfig(s.snapshotKey("config"), cfg, disk); err != nil {
+			return err
+		}
+		settings, err := configSettings(cfg)
+		if err != nil {
+			return err
+		}
+		novels, err := rawEntries(cfg.Novels)
+		if err != nil {
+			return err
+		}
+		if err := writeRows(tx, "settings", "key", "value", oldSettings, settings); err != nil {
+			return err
+		}
+		return writeRows(tx, "novels", "path", "data", oldNovels, novels)
+	})
+	if err != nil {
+		return err
+	}
+	return recordConfig(s.snapshotKey("config"), cfg)
+}
+
+func (s *sqliteStorage) LoadProgress() (ProgressData, error) {
+	progress, err := loadProgress(s.db)
+	if err != nil {
+		return nil, err
+	}
+	return progress, recordProgress(s.snapshotKey("progress"), progress)
+}
+
+func (s *sqliteStorage) SaveProgress(progress ProgressData) error {
+	err := s.inTx(func(tx *sql.Tx) error {
+		disk, err := loadProgress(tx)
+		if err != nil {
+			return err
+		}
+		old, err := rawEntries(disk)
+		if err != nil {
+			return err
+		}
+		if err := mergeProgress(s.snapshotKey("progress"), progress, disk); err != nil {
+			return err
+		}
+		current, err := rawEntries(progress)
+		if err != nil {
+			return err
+		}
+		return writeRows(tx, "progress", "path", "data", old, current)
+	})
+	if err != nil {
+		return err
+	}
+	return recordProgress(s.snapshotKey("progress"), progress)
+}
+
+func (s *sqliteStorage) Bookmarks(novelID string) ([]Bookmark, error) {
+	rows, err := s.db.Query(`SELECT novel_id, name, chapter, segment, snippet, note, created_at
+		FROM bookmarks WHERE ? = '' OR novel_id = ? ORDER BY created_at`, novelID, novelID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var bookmarks []Bookmark
+	for rows.Next() {
+		var b Bookmark
+		var created string
+		if err := rows.Scan(&b.NovelID, &b.Name, &b.Chapter, &b.Segment, &b.Snippet, &b.Note, &created); err != nil {
+			return nil, err
+		}
+		b.CreatedAt, _ = time.Parse(sqliteTimeFormat, created)
+		bookmarks = append(bookmarks, b)
+	}
+	return bookmarks, rows.Err()
+}
+
+func (s *sqliteStorage) AddBookmark(b Bookmark) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		var exists int
+		err := tx.QueryRow("SELECT 1 FROM bookmarks WHERE novel_id = ? AND name = ?", b.NovelID, b.Name).Scan(&exists)
+		if err == nil {
+			return ErrExists
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO bookmarks (novel_id, name, chapter, segment, snippet, note, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			b.NovelID, b.Name, b.Chapter, b.Segment, b.Snippet, b.Note, b.CreatedAt.UTC().Format(sqliteTimeFormat))
+		return err
+	})
+}
+
+func (s *sqliteStorage) RemoveBookmark(novelID, name string) error {
+	res, err := s.db.Exec("DELETE FROM bookmarks WHERE novel_id = ? AND name = ?", novelID, name)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *sqliteStorage) AddSession(session Session) error {
+	_, err := s.db.Exec(`INSERT INTO sessions (novel_id, start_time, end_time, start_chapter, start_segment,
+		end_chapter, end_segment, segments, chars, backend, voice) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		session.NovelID, session.Start.UTC().Format(sqliteTimeFormat), session.End.UTC().Format(sqliteTimeFormat),
+		session.StartChapter, session.StartSegment, session.EndChapter, session.EndSegment,
+		session.Segments, session.Chars, session.Backend, session.Voice)
+	return err
+}
+
+func (s *sqliteStorage) Sessions(since time.Time) ([]Session, error) {
+	rows, err := s.db.Query(`SELECT novel_id, start_time, end_time, start_chapter, start_segment,
+		end_chapter, end_segment, segments, chars, backend, voice
+		FROM sessions WHERE start_time >= ? ORDER BY start_time`, since.UTC().Format(sqliteTimeFormat))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var sessions []Session
+	for rows.Next() {
+		var ss Session
+		var start, end string
+		if err := rows.Scan(&ss.NovelID, &start, &end, &ss.StartChapter, &ss.StartSegment,
+			&ss.EndChapter, &ss.EndSegment, &ss.Segments, &ss.Chars, &ss.Backend, &ss.Voice); err != nil {
+			return nil, err
+		}
+		ss.Start, _ = time.Parse(sqliteTimeFormat, start)
+		ss.End, _ = time.Parse(sqliteTimeFormat, end)
+		sessions = append(sessions, ss)
+	}
+	return sessions, rows.Err()
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"bufio"
+	"cmp"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -8,10 +11,12 @@ import (
 	"os/signal"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/xqbumu/go-novel-reader/config"
 	"github.com/xqbumu/go-novel-reader/novel"
@@ -19,45 +24,51 @@ import (
 )
 
 var (
+	dataDir string         // Directory holding configuration, progress and history
+	store   config.Storage // Backend the data is kept in (JSON files or SQLite)
+
 	cfg         *config.AppConfig
-	configPath  string
 	configDirty bool // Flag to track if main config needs saving
 
 	progressData  config.ProgressData
-	progressPath  string
 	progressDirty bool // Flag to track if progress data needs saving
 
 	activeNovel *config.NovelInfo // Holds the currently active novel's *metadata*
+
+	readerLock *config.ReaderLock // Held while this process reads aloud
+
+	session *config.Session // Reading session being logged, nil until a segment is spoken
 )
 
-// Map regex names back to actual regex objects
-var regexMap = map[string]*regexp.Regexp{
-	"chinese":  novel.ChapterRegexes["chinese"],
-	"english":  novel.ChapterRegexes["english"],
-	"markdown": novel.ChapterRegexes["markdown"],
-}
+// takeOverTimeout is how long to wait for another reading instance to exit.
+const takeOverTimeout = 5 * time.Second
+
+// maxListedNewChapters limits how many new chapters are listed after a file change.
+const maxListedNewChapters = 10
 
 // Define segment separator
 var segmentSeparator = regexp.MustCompile(`\n+`)
 
 func main() {
-	// --- Configuration Loading ---
+	// --- Storage ---
 	var err error
-	configPath, err = config.DefaultConfigPath()
+	dataDir, err = config.DefaultDir()
 	if err != nil {
-		log.Fatalf("Error getting default config path: %v", err)
+		log.Fatalf("Error getting default config directory: %v", err)
 	}
-	cfg, err = config.LoadConfig(configPath)
+	store, err = config.OpenStorage(dataDir)
 	if err != nil {
-		log.Fatalf("Error loading config: %v", err)
+		log.Fatalf("Error opening storage: %v", err)
 	}
 
-	// --- Progress Data Loading ---
-	progressPath, err = config.DefaultProgressPath()
+	// --- Configuration Loading ---
+	cfg, err = store.LoadConfig()
 	if err != nil {
-		log.Fatalf("Error getting default progress path: %v", err)
+		log.Fatalf("Error loading config: %v", err)
 	}
-	progressData, err = config.LoadProgress(progressPath)
+
+	// --- Progress Data Loading ---
+	progressData, err = store.LoadProgress()
 	if err != nil {
 		log.Fatalf("Error loading progress data: %v", err) // Fatal on progress load error too
 	}
@@ -68,6 +79,7 @@ func main() {
 	// --- Defer Save on Normal Exit ---
 	defer func() {
 		saveOnExit() // Call combined save function
+		store.Close()
 	}()
 
 	// --- Load Active Novel Metadata ---
@@ -81,17 +93,60 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Manages and reads novels using macOS TTS.\n\n")
 		fmt.Fprintf(os.Stderr, "Commands:\n")
 		fmt.Fprintf(os.Stderr, "  add <filepath>      Add a new novel, parse chapters, and set as active.\n")
-		fmt.Fprintf(os.Stderr, "  list                List novels in the library with index and last read chapter/segment.\n")
-		fmt.Fprintf(os.Stderr, "  remove <index>      Remove the novel at the specified index (from 'list').\n")
-		fmt.Fprintf(os.Stderr, "  switch <index>      Set the novel at the specified index (from 'list') as active.\n")
+		fmt.Fprintf(os.Stderr, "                      The path may be a directory of chapter files (001.txt, 002.txt, ...),\n")
+		fmt.Fprintf(os.Stderr, "                      or a zip, tar, tar.gz or gz archive; use --member <name> to pick the file inside.\n")
+		fmt.Fprintf(os.Stderr, "  list [--status <unread|reading|finished>]\n")
+		fmt.Fprintf(os.Stderr, "                      List novels in the library with ID, status and last read chapter/segment.\n")
+		fmt.Fprintf(os.Stderr, "  remove <novel>      Remove a novel from the library.\n")
+		fmt.Fprintf(os.Stderr, "  switch <novel>      Set a novel as active.\n")
+		fmt.Fprintf(os.Stderr, "                      <novel> is an ID (from 'list'), an exact title, or part of the title or file name.\n")
 		fmt.Fprintf(os.Stderr, "  chapters            List chapters of the active novel.\n")
-		fmt.Fprintf(os.Stderr, "  read [chap_index]   Read active novel segment by segment. Starts from specified chapter (1-based index)\n")
-		fmt.Fprintf(os.Stderr, "                      or continues from the last read chapter/segment if index is omitted.\n")
+		fmt.Fprintf(os.Stderr, "  read [position] [--from-bookmark <name>]\n")
+		fmt.Fprintf(os.Stderr, "                      Read active novel segment by segment, from the given position or bookmark,\n")
+		fmt.Fprintf(os.Stderr, "                      or from the last read chapter/segment if both are omitted. A position is\n")
+		fmt.Fprintf(os.Stderr, "                      12 (chapter), 12:34 (chapter:segment), 45%%, +3/-1 (chapters), +10s/-10s\n")
+		fmt.Fprintf(os.Stderr, "                      (segments) or part of a chapter title.\n")
+		fmt.Fprintf(os.Stderr, "                      Stop on its own with --to <chapter>, --chapters N, --for 30m, or a sleep timer\n")
+		fmt.Fprintf(os.Stderr, "                      --sleep 20m [--finish-chapter]; the voice fades out before stopping. Limits\n")
+		fmt.Fprintf(os.Stderr, "                      keep reading across chapters even with auto_next off.\n")
+		fmt.Fprintf(os.Stderr, "  seek <position> [--from-bookmark <name>]\n")
+		fmt.Fprintf(os.Stderr, "                      Move the reading position like 'read' without reading.\n")
+		fmt.Fprintf(os.Stderr, "  play                Read the active novel with live keyboard controls: space pause/resume,\n")
+		fmt.Fprintf(os.Stderr, "                      left/right previous/next segment, up/down previous/next chapter, r replay,\n")
+		fmt.Fprintf(os.Stderr, "                      +/- speed, b bookmark, q quit. A status line shows position, percentage and ETA.\n")
+		fmt.Fprintf(os.Stderr, "  tui                 Full-screen interface: library with progress, chapter tree and a reading pane\n")
+		fmt.Fprintf(os.Stderr, "                      that follows the segment being spoken; switch novels, jump to chapters,\n")
+		fmt.Fprintf(os.Stderr, "                      search (/) and change settings (s) without leaving it.\n")
+		fmt.Fprintf(os.Stderr, "  view                Page through the active novel silently from the last read position.\n")
+		fmt.Fprintf(os.Stderr, "                      The page's first segment becomes the reading position shared with 'read'.\n")
 		fmt.Fprintf(os.Stderr, "  next                Read the next chapter of the active novel (starts from segment 0).\n")
 		fmt.Fprintf(os.Stderr, "  prev                Read the previous chapter of the active novel (starts from segment 0).\n")
+		fmt.Fprintf(os.Stderr, "  next-seg, prev-seg  Read from the segment after or before the last read one.\n")
 		fmt.Fprintf(os.Stderr, "  where               Show the active novel and the last read chapter/segment index.\n")
 		fmt.Fprintf(os.Stderr, "  config [setting]    View or toggle configuration settings.\n")
-		fmt.Fprintf(os.Stderr, "                      Available settings: auto_next (toggle auto-read next segment/chapter)\n")
+		fmt.Fprintf(os.Stderr, "                      Available settings: auto_next (toggle auto-read next segment/chapter),\n")
+		fmt.Fprintf(os.Stderr, "                      roots [add|rm <dir>] (library roots searched for moved novels),\n")
+		fmt.Fprintf(os.Stderr, "                      voice [name] (TTS voice, see 'say -v ?'; no name uses the system voice),\n")
+		fmt.Fprintf(os.Stderr, "                      rate [wpm] (speaking rate in words per minute; no value uses the voice's rate),\n")
+		fmt.Fprintf(os.Stderr, "                      recap [off|segments <n>|seconds <n>|cue|after <duration>|rate [wpm]]\n")
+		fmt.Fprintf(os.Stderr, "                      (what 'read' recaps when resuming after a break, 12h unless set with after)\n")
+		fmt.Fprintf(os.Stderr, "  relocate [novel] [path] [--root <dir>] [--force]\n")
+		fmt.Fprintf(os.Stderr, "                      Find moved novels by content in the library roots (and --root), or point a\n")
+		fmt.Fprintf(os.Stderr, "                      novel to its new path. Progress is kept.\n")
+		fmt.Fprintf(os.Stderr, "  bookmark [subcommand]\n")
+		fmt.Fprintf(os.Stderr, "                      Bookmarks of the active novel: add [name] [--note text] [--chapter N] [--segment M],\n")
+		fmt.Fprintf(os.Stderr, "                      list [--all], rm <name>, goto <name> (starts reading there), export [file.md].\n")
+		fmt.Fprintf(os.Stderr, "                      While reading, type 'b' [name] and Enter to bookmark the segment being spoken.\n")
+		fmt.Fprintf(os.Stderr, "  search <query> [--all] [--regex] [--goto N]\n")
+		fmt.Fprintf(os.Stderr, "                      Find text in the active novel (or every novel with --all), ignoring case and\n")
+		fmt.Fprintf(os.Stderr, "                      full-width forms. --goto N moves the reading position to hit N.\n")
+		fmt.Fprintf(os.Stderr, "  stats               Show listening time, speaking rate, streaks, per-novel totals and time left.\n")
+		fmt.Fprintf(os.Stderr, "  migrate-storage <json|sqlite>\n")
+		fmt.Fprintf(os.Stderr, "                      Move all data to JSON files or to a SQLite database (library.db).\n")
+		fmt.Fprintf(os.Stderr, "  reflow [mode]       Show or set how the active novel's hard-wrapped lines are joined: auto, on or off.\n")
+		fmt.Fprintf(os.Stderr, "  filter [subcommand] Manage text filters applied before display and speech:\n")
+		fmt.Fprintf(os.Stderr, "                      list, add <name> <pattern> [replacement], rm <name>,\n")
+		fmt.Fprintf(os.Stderr, "                      enable <name>, disable <name> (enable/disable apply to the active novel)\n")
 		fmt.Fprintf(os.Stderr, "\n")
 	}
 
@@ -110,7 +165,7 @@ func main() {
 	case "add":
 		handleAdd(args)
 	case "list":
-		handleListNovels()
+		handleListNovels(args)
 	case "remove":
 		handleRemove(args)
 	case "switch":
@@ -119,14 +174,40 @@ func main() {
 		handleChapters()
 	case "read", "continue":
 		handleRead(args)
+	case "play":
+		handlePlay(args)
+	case "tui":
+		handleTUI()
+	case "view":
+		handleView()
+	case "seek":
+		handleSeek(args)
 	case "next":
 		handleNext()
 	case "prev":
 		handlePrev()
+	case "next-seg":
+		handleNextSegment()
+	case "prev-seg":
+		handlePrevSegment()
 	case "where":
 		handleWhere()
 	case "config":
 		handleConfig(args)
+	case "filter":
+		handleFilter(args)
+	case "reflow":
+		handleReflow(args)
+	case "relocate":
+		handleRelocate(args)
+	case "migrate-storage":
+		handleMigrateStorage(args)
+	case "stats":
+		handleStats()
+	case "bookmark", "bookmarks":
+		handleBookmark(args)
+	case "search":
+		handleSearch(args)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
 		flag.Usage()
@@ -140,6 +221,15 @@ func setupSignalHandler() {
 	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		sig := <-sigs
+		if cancelRead != nil {
+			// Let 'read' stop speaking and return; a second signal exits at once
+			fmt.Printf("\nReceived signal: %s. Stopping...\n", sig)
+			cancelRead()
+			sig = <-sigs
+		}
+		if restoreTerminal != nil {
+			restoreTerminal()
+		}
 		fmt.Printf("\nReceived signal: %s. Exiting...\n", sig)
 		saveOnExit() // Call combined save function
 		os.Exit(0)
@@ -147,7 +237,9 @@ func setupSignalHandler() {
 }
 
 // saveOnExit checks dirty flags and saves config/progress if needed.
+// A reading session in progress is logged as well.
 func saveOnExit() {
+	endSession()
 	if progressDirty {
 		fmt.Println("Progress changed, saving before exit...")
 		saveProgress()
@@ -164,6 +256,10 @@ func handleConfig(args []string) {
 	if len(args) == 0 {
 		fmt.Println("Current Configuration:")
 		fmt.Printf("  auto_next: %t\n", cfg.AutoReadNext)
+		fmt.Printf("  roots: %s\n", strings.Join(cfg.LibraryRoots, ", "))
+		fmt.Printf("  voice: %s\n", cmp.Or(cfg.Voice, "(system default)"))
+		fmt.Printf("  rate: %s\n", rateName(cfg.Rate))
+		fmt.Printf("  recap: %s\n", recapName(cfg.Recap))
 		return
 	}
 	setting := args[0]
@@ -172,12 +268,221 @@ func handleConfig(args []string) {
 		cfg.AutoReadNext = !cfg.AutoReadNext
 		configDirty = true // Mark main config as dirty
 		fmt.Printf("Set auto_next to: %t\n", cfg.AutoReadNext)
+	case "roots":
+		handleRoots(args[1:])
+	case "voice":
+		cfg.Voice = strings.Join(args[1:], " ") // No name resets to the system voice
+		configDirty = true
+		fmt.Printf("Set voice to: %s\n", cmp.Or(cfg.Voice, "(system default)"))
+	case "rate":
+		rate := 0 // No value resets to the voice's rate
+		if len(args) > 1 {
+			var err error
+			rate, err = strconv.Atoi(args[1])
+			if err != nil || rate < tts.MinRate || rate > tts.MaxRate {
+				log.Fatalf("Error: Invalid rate '%s'. Please give words per minute between %d and %d.", args[1], tts.MinRate, tts.MaxRate)
+			}
+		}
+		cfg.Rate = rate
+		configDirty = true
+		fmt.Printf("Set rate to: %s\n", rateName(cfg.Rate))
+	case "recap":
+		handleRecapConfig(args[1:])
 	default:
-		log.Fatalf("Error: Unknown config setting '%s'. Available: auto_next", setting)
+		log.Fatalf("Error: Unknown config setting '%s'. Available: auto_next, roots, voice, rate, recap", setting)
+	}
+}
+
+// rateName describes a speaking rate setting.
+func rateName(rate int) string {
+	if rate == 0 {
+		return "(voice default)"
+	}
+	return fmt.Sprintf("%d wpm", rate)
+}
+
+// handleMigrateStorage moves all data to another storage backend.
+func handleMigrateStorage(args []string) {
+	if len(args) < 1 {
+		fmt.Printf("Data is stored as %s in %s.\n", store.Backend(), store.Location())
+		fmt.Printf("Use 'migrate-storage %s' or 'migrate-storage %s' to move it.\n", config.BackendJSON, config.BackendSQLite)
+		return
+	}
+	// A reading instance would keep writing to the old backend.
+	if !acquireReaderLock() {
+		log.Fatal("Error: Stop the reading instance before migrating storage.")
+	}
+	// Pending changes go to the current backend first, so they are migrated too.
+	saveOnExit()
+	store.Close()
+	newStore, err := config.MigrateStorage(dataDir, args[0])
+	if err != nil {
+		log.Fatalf("Error migrating storage: %v", err)
+	}
+	store = newStore
+	fmt.Printf("Moved all data to %s (%s). The previous files are in the backups directory.\n", store.Location(), store.Backend())
+}
+
+// handleRoots lists, adds or removes the library roots searched for moved novels.
+func handleRoots(args []string) {
+	if len(args) == 0 {
+		if len(cfg.LibraryRoots) == 0 {
+			fmt.Println("No library roots configured. Use 'config roots add <dir>'.")
+		}
+		for _, root := range cfg.LibraryRoots {
+			fmt.Println(root)
+		}
+		return
+	}
+	if len(args) < 2 {
+		log.Fatalf("Error: config roots %s requires a directory.", args[0])
+	}
+	dir, err := filepath.Abs(args[1])
+	if err != nil {
+		log.Fatalf("Error getting absolute path for %s: %v", args[1], err)
+	}
+	switch args[0] {
+	case "add":
+		if slices.Contains(cfg.LibraryRoots, dir) {
+			fmt.Printf("'%s' is already a library root.\n", dir)
+			return
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			log.Fatalf("Error: Not a directory: %s", dir)
+		}
+		cfg.LibraryRoots = append(cfg.LibraryRoots, dir)
+		fmt.Printf("Added library root: %s\n", dir)
+	case "rm", "remove":
+		if !slices.Contains(cfg.LibraryRoots, dir) {
+			log.Fatalf("Error: '%s' is not a library root.", dir)
+		}
+		cfg.LibraryRoots = removeString(cfg.LibraryRoots, dir)
+		fmt.Printf("Removed library root: %s\n", dir)
+	default:
+		log.Fatalf("Error: Unknown roots subcommand '%s'. Available: add, rm", args[0])
+	}
+	configDirty = true
+}
+
+func handleReflow(args []string) {
+	if activeNovel == nil {
+		fmt.Println("No active novel selected. Use 'switch <novel>' first.")
+		return
+	}
+	mode := activeNovel.Reflow
+	if mode == "" {
+		mode = novel.ReflowAuto
+	}
+	if len(args) == 0 {
+		fmt.Printf("Reflow for '%s': %s\n", filepath.Base(activeNovel.FilePath), mode)
+		return
+	}
+	switch args[0] {
+	case novel.ReflowAuto, novel.ReflowOn, novel.ReflowOff:
+	default:
+		log.Fatalf("Error: Unknown reflow mode '%s'. Available: auto, on, off", args[0])
+	}
+	if args[0] == mode {
+		fmt.Printf("Reflow is already %s.\n", mode)
+		return
+	}
+
+	activeNovel.Reflow = args[0]
+	if activeNovel.Reflow == novel.ReflowAuto {
+		activeNovel.Reflow = "" // Auto is the default
+	}
+	activeNovel.ChapterHashes = nil // Content hashes change with the layout; don't report it as new chapters
+	configDirty = true
+	// Segments are re-split, so a position inside the chapter no longer applies.
+	if progInfo, ok := progressData[activeNovel.FilePath]; ok && progInfo.LastReadSegmentIndex != 0 {
+		progInfo.LastReadSegmentIndex = 0
+		progressDirty = true
+		fmt.Println("Segment layout changed, progress reset to the start of the chapter.")
+	}
+	fmt.Printf("Set reflow for '%s' to: %s\n", filepath.Base(activeNovel.FilePath), args[0])
+}
+
+func handleFilter(args []string) {
+	if len(args) == 0 || args[0] == "list" {
+		fmt.Printf("Built-in filters: %s\n", strings.Join(novel.BuiltinFilterNames(), ", "))
+		if len(cfg.FilterRules) > 0 {
+			fmt.Println("User rules:")
+			for _, rule := range cfg.FilterRules {
+				fmt.Printf("  %s: %q -> %q\n", rule.Name, rule.Pattern, rule.Replace)
+			}
+		}
+		if activeNovel != nil {
+			fmt.Printf("Enabled for '%s': %s\n", filepath.Base(activeNovel.FilePath), strings.Join(enabledFilters(activeNovel), ", "))
+		}
+		return
+	}
+
+	switch args[0] {
+	case "add":
+		if len(args) < 3 {
+			log.Fatal("Error: filter add requires a name and a pattern.")
+		}
+		name, pattern := args[1], args[2]
+		if _, builtin := novel.BuiltinFilters[name]; builtin || findFilterRule(name) != nil {
+			log.Fatalf("Error: A filter named '%s' already exists.", name)
+		}
+		if _, err := novel.NewRegexFilter(pattern, ""); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		rule := config.FilterRule{Name: name, Pattern: pattern}
+		if len(args) > 3 {
+			rule.Replace = args[3]
+		}
+		cfg.FilterRules = append(cfg.FilterRules, rule)
+		configDirty = true
+		fmt.Printf("Added filter rule '%s'. Use 'filter enable %s' to apply it to the active novel.\n", name, name)
+	case "rm", "remove":
+		if len(args) < 2 {
+			log.Fatal("Error: filter rm requires a rule name.")
+		}
+		name := args[1]
+		if findFilterRule(name) == nil {
+			log.Fatalf("Error: No user filter rule named '%s'.", name)
+		}
+		rules := cfg.FilterRules[:0]
+		for _, rule := range cfg.FilterRules {
+			if rule.Name != name {
+				rules = append(rules, rule)
+			}
+		}
+		cfg.FilterRules = rules
+		for _, info := range cfg.Novels {
+			info.Filters = removeString(info.Filters, name)
+		}
+		configDirty = true
+		fmt.Printf("Removed filter rule '%s'.\n", name)
+	case "enable", "disable":
+		if len(args) < 2 {
+			log.Fatalf("Error: filter %s requires a filter name.", args[0])
+		}
+		if activeNovel == nil {
+			log.Fatal("Error: No active novel selected.")
+		}
+		name := args[1]
+		if _, builtin := novel.BuiltinFilters[name]; !builtin && findFilterRule(name) == nil {
+			log.Fatalf("Error: Unknown filter '%s'. See 'filter list'.", name)
+		}
+		filters := removeString(enabledFilters(activeNovel), name)
+		if args[0] == "enable" {
+			filters = append(filters, name)
+		}
+		activeNovel.Filters = filters
+		configDirty = true
+		fmt.Printf("Filters for '%s': %s\n", filepath.Base(activeNovel.FilePath), strings.Join(filters, ", "))
+	default:
+		log.Fatalf("Error: Unknown filter subcommand '%s'. Available: list, add, rm, enable, disable", args[0])
 	}
 }
 
 func handleAdd(args []string) {
+	fs := flag.NewFlagSet("add", flag.ExitOnError)
+	member := fs.String("member", "", "file inside an archive to read (prompted for when the archive holds several)")
+	args = parseCommandFlags(fs, args)
 	if len(args) < 1 {
 		log.Fatal("Error: add command requires a filepath argument.")
 	}
@@ -186,7 +491,12 @@ func handleAdd(args []string) {
 		log.Fatalf("Error getting absolute path for %s: %v", args[0], err)
 	}
 
-	if _, exists := cfg.Novels[filePath]; exists {
+	if existing, exists := cfg.Novels[filePath]; exists {
+		// Novels are keyed by their file, so an archive holds at most one of them.
+		if *member != "" && *member != existing.ArchiveMember {
+			log.Fatalf("Error: '%s' is already in the library with member '%s' (ID %s). Only one novel per archive is supported; extract '%s' to add it.",
+				filePath, cmp.Or(existing.ArchiveMember, "(all files)"), existing.ID, *member)
+		}
 		log.Printf("Novel '%s' already exists in the library.", filePath)
 		return
 	}
@@ -195,39 +505,46 @@ func handleAdd(args []string) {
 	}
 
 	fmt.Printf("Adding novel: %s\n", filePath)
-	detectedFormatRegex, err := novel.DetectFormat(filePath)
+	src := novel.Source{Path: filePath, Member: *member}
+	if novel.IsArchive(filePath) && src.Member == "" {
+		src.Member = selectArchiveMember(filePath)
+	}
+	format, err := novel.DetectFormat(src)
 	if err != nil {
 		log.Fatalf("Error detecting format: %v", err)
 	}
-	detectedRegexName := ""
-	for name, r := range regexMap {
-		if r == detectedFormatRegex {
-			detectedRegexName = name
-			break
-		}
-	}
-	if detectedRegexName == "" {
-		log.Println("Warning: Could not map detected regex back to a known name. Using default.")
-		detectedRegexName = "markdown"
-		detectedFormatRegex = regexMap[detectedRegexName]
+	if format.Strategy == novel.StrategyRegex {
+		fmt.Printf("Detected format: %s\n", format.Name)
+	} else {
+		fmt.Printf("Detected format: %s split\n", format.Strategy)
 	}
-	fmt.Printf("Detected format: %s\n", detectedRegexName)
 
-	parsedChapters, err := novel.ParseNovel(filePath, detectedFormatRegex)
+	// Create metadata entry
+	newNovelInfo := &config.NovelInfo{
+		ID:            config.NewNovelID(cfg.Novels),
+		Title:         src.Title(),
+		FilePath:      filePath,
+		ArchiveMember: src.Member,
+		DetectedRegex: format.Name,
+		SplitStrategy: format.Strategy,
+		ChunkSize:     format.ChunkSize,
+	}
+	parsedChapters, err := parseNovel(newNovelInfo)
 	if err != nil {
 		log.Fatalf("Error parsing novel: %v", err)
 	}
-	chapterTitles := make([]string, len(parsedChapters))
+	newNovelInfo.Chapters = parsedChapters // Keep chapters in memory for active novel
+	newNovelInfo.ChapterTitles = make([]string, len(parsedChapters))
 	for i, ch := range parsedChapters {
-		chapterTitles[i] = ch.Title
+		newNovelInfo.ChapterTitles[i] = ch.Title
 	}
-
-	// Create metadata entry
-	newNovelInfo := &config.NovelInfo{
-		FilePath:      filePath,
-		Chapters:      parsedChapters, // Keep chapters in memory for active novel
-		ChapterTitles: chapterTitles,
-		DetectedRegex: detectedRegexName,
+	newNovelInfo.ChapterHashes = novel.ChapterHashes(parsedChapters)
+	if size, modTime, err := src.Stat(); err == nil {
+		newNovelInfo.FileSize = size
+		newNovelInfo.ModTime = modTime
+	}
+	if fp, err := novel.Fingerprint(src); err == nil {
+		newNovelInfo.Fingerprint = fp
 	}
 	cfg.Novels[filePath] = newNovelInfo
 	cfg.ActiveNovelPath = filePath
@@ -240,17 +557,25 @@ func handleAdd(args []string) {
 		progressDirty = true // Mark progress dirty
 	}
 
-	fmt.Printf("Successfully added '%s' with %d chapters and set as active.\n", filePath, len(parsedChapters))
+	fmt.Printf("Successfully added '%s' (ID %s) with %d chapters and set as active.\n", filePath, newNovelInfo.ID, len(parsedChapters))
 }
 
-func handleListNovels() {
+func handleListNovels(args []string) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	status := fs.String("status", "", "only list novels with this `status`: unread, reading or finished")
+	parseCommandFlags(fs, args)
+	if *status != "" && !slices.Contains(config.NovelStatuses, config.NovelStatus(*status)) {
+		log.Fatalf("Error: Unknown status '%s'. Available: unread, reading, finished", *status)
+	}
+
 	if len(cfg.Novels) == 0 {
 		fmt.Println("Library is empty. Use 'add <filepath>' to add a novel.")
 		return
 	}
 	fmt.Println("Novels in library:")
 	sortedNovels := getNovelsSorted()
-	for i, novelInfo := range sortedNovels {
+	listed := 0
+	for _, novelInfo := range sortedNovels {
 		activeMarker := " "
 		if novelInfo.FilePath == cfg.ActiveNovelPath {
 			activeMarker = "*"
@@ -261,33 +586,46 @@ func handleListNovels() {
 			// Should not happen if add creates progress, but handle defensively
 			progInfo = &config.ProgressInfo{LastReadChapterIndex: 0, LastReadSegmentIndex: 0}
 		}
-		fmt.Printf(" %s %d: %s (%d chapters, last read: Ch %d, Seg %d)\n",
-			activeMarker, i+1, filepath.Base(novelInfo.FilePath), len(novelInfo.ChapterTitles),
-			progInfo.LastReadChapterIndex+1, progInfo.LastReadSegmentIndex)
+		novelStatus := progInfo.Status(novelInfo.ChapterHashes)
+		if *status != "" && novelStatus != config.NovelStatus(*status) {
+			continue
+		}
+		listed++
+		fmt.Printf(" %s %s: %s [%s] (%d chapters, %s",
+			activeMarker, novelInfo.ID, novelInfo.Title, filepath.Base(novelInfo.FilePath), len(novelInfo.ChapterTitles), novelStatus)
+		if novelStatus == config.StatusReading {
+			fmt.Printf(": Ch %d, Seg %d, %d chapters completed",
+				progInfo.LastReadChapterIndex+1, progInfo.LastReadSegmentIndex, completedChapters(novelInfo, progInfo))
+		}
+		fmt.Println(")")
+	}
+	if listed == 0 {
+		fmt.Printf("No %s novels.\n", *status)
 	}
 }
 
-func handleRemove(args []string) {
-	if len(args) < 1 {
-		log.Fatal("Error: remove command requires an index argument.")
-	}
-	index, err := strconv.Atoi(args[0])
-	if err != nil {
-		log.Fatalf("Error: Invalid index '%s'. Please provide the number shown by 'list'.", args[0])
+// completedChapters counts the chapters of a novel heard to their end.
+func completedChapters(info *config.NovelInfo, progInfo *config.ProgressInfo) int {
+	count := 0
+	for _, hash := range info.ChapterHashes {
+		if progInfo.Completed(hash) {
+			count++
+		}
 	}
+	return count
+}
 
-	sortedNovels := getNovelsSorted()
-	if index < 1 || index > len(sortedNovels) {
-		log.Fatalf("Error: Index %d is out of range. Valid range is 1 to %d.", index, len(sortedNovels))
+func handleRemove(args []string) {
+	if len(args) < 1 {
+		log.Fatal("Error: remove command requires a novel argument (ID, title or part of it).")
 	}
-
-	novelToRemove := sortedNovels[index-1]
+	novelToRemove := mustResolveNovel(strings.Join(args, " "))
 	filePath := novelToRemove.FilePath
 
 	// Remove from main config
 	delete(cfg.Novels, filePath)
 	configDirty = true
-	fmt.Printf("Removed novel metadata %d: %s\n", index, filepath.Base(filePath))
+	fmt.Printf("Removed novel metadata %s: %s\n", novelToRemove.ID, novelToRemove.Title)
 
 	// Remove from progress data
 	if _, exists := progressData[filePath]; exists {
@@ -296,6 +634,11 @@ func handleRemove(args []string) {
 		fmt.Printf("Removed novel progress data for: %s\n", filepath.Base(filePath))
 	}
 
+	removeBookmarks(novelToRemove.ID)
+	if err := os.Remove(searchIndexPath(novelToRemove)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: Could not remove the search index: %v", err)
+	}
+
 	if cfg.ActiveNovelPath == filePath {
 		cfg.ActiveNovelPath = ""
 		activeNovel = nil
@@ -306,19 +649,9 @@ func handleRemove(args []string) {
 
 func handleSwitch(args []string) {
 	if len(args) < 1 {
-		log.Fatal("Error: switch command requires an index argument.")
+		log.Fatal("Error: switch command requires a novel argument (ID, title or part of it).")
 	}
-	index, err := strconv.Atoi(args[0])
-	if err != nil {
-		log.Fatalf("Error: Invalid index '%s'. Please provide the number shown by 'list'.", args[0])
-	}
-
-	sortedNovels := getNovelsSorted()
-	if index < 1 || index > len(sortedNovels) {
-		log.Fatalf("Error: Index %d is out of range. Valid range is 1 to %d.", index, len(sortedNovels))
-	}
-
-	novelToSwitch := sortedNovels[index-1]
+	novelToSwitch := mustResolveNovel(strings.Join(args, " "))
 	filePath := novelToSwitch.FilePath
 
 	if cfg.ActiveNovelPath != filePath {
@@ -346,7 +679,7 @@ func handleSwitch(args []string) {
 
 func handleChapters() {
 	if activeNovel == nil {
-		fmt.Println("No active novel selected. Use 'switch <index>' first.")
+		fmt.Println("No active novel selected. Use 'switch <novel>' first.")
 		return
 	}
 	loadActiveNovelChapters() // Ensure chapters are loaded into activeNovel.Chapters
@@ -361,8 +694,16 @@ func handleChapters() {
 }
 
 func handleRead(args []string) {
+	fs := flag.NewFlagSet("read", flag.ExitOnError)
+	fromBookmark := fs.String("from-bookmark", "", "start at the bookmark with this `name`")
+	limitsFor := limitFlags(fs)
+	spec := strings.Join(parseCommandFlags(fs, args), " ")
+
 	if activeNovel == nil {
-		fmt.Println("No active novel selected. Use 'switch <index>' first.")
+		fmt.Println("No active novel selected. Use 'switch <novel>' first.")
+		return
+	}
+	if !acquireReaderLock() {
 		return
 	}
 	loadActiveNovelChapters()
@@ -371,180 +712,75 @@ func handleRead(args []string) {
 		return
 	}
 
-	// Get current progress for the active novel
-	currentProgress, ok := progressData[activeNovel.FilePath]
-	if !ok {
-		// Initialize if missing (should not happen if 'add' worked)
-		currentProgress = &config.ProgressInfo{LastReadChapterIndex: 0, LastReadSegmentIndex: 0}
-		progressData[activeNovel.FilePath] = currentProgress
-		progressDirty = true
-	}
-
-	targetChapterIndex := currentProgress.LastReadChapterIndex
-	startSegmentIndex := currentProgress.LastReadSegmentIndex
-	chapterChanged := false
-
-	if len(args) > 0 {
-		idx, err := strconv.Atoi(args[0])
-		if err != nil || idx < 1 || idx > len(activeNovel.Chapters) {
-			log.Fatalf("Error: Invalid chapter index '%s'. Please provide a number between 1 and %d.", args[0], len(activeNovel.Chapters))
-		}
-		newChapterIndex := idx - 1
-		if newChapterIndex != targetChapterIndex {
-			targetChapterIndex = newChapterIndex
-			startSegmentIndex = 0 // Reset segment on chapter change
-			chapterChanged = true
-		}
-	}
-
-	// Immediate Save on Chapter Change
-	if chapterChanged {
-		fmt.Printf("Switching to Chapter %d, saving progress...\n", targetChapterIndex+1)
-		currentProgress.LastReadChapterIndex = targetChapterIndex
-		currentProgress.LastReadSegmentIndex = startSegmentIndex // Reset segment index
-		progressDirty = true
-		saveProgress() // Save progress immediately
-	}
-
-	// Validate targetChapterIndex (could be from loaded progress or args)
-	if targetChapterIndex < 0 || targetChapterIndex >= len(activeNovel.Chapters) {
-		fmt.Printf("Last read chapter index (%d) is invalid. Reading first chapter.\n", targetChapterIndex+1)
-		targetChapterIndex = 0
-		startSegmentIndex = 0
-		if currentProgress.LastReadChapterIndex != 0 || currentProgress.LastReadSegmentIndex != 0 {
-			currentProgress.LastReadChapterIndex = 0
-			currentProgress.LastReadSegmentIndex = 0
-			progressDirty = true
-			saveProgress() // Save corrected progress
-		}
-	}
-
-	chapter := activeNovel.Chapters[targetChapterIndex]
-	fmt.Printf("--- Reading Chapter %d: %s ---\n", targetChapterIndex+1, chapter.Title)
-
-	segmentsReadInSession := 0
-	segments := segmentSeparator.Split(chapter.Content, -1)
-	if len(segments) == 0 {
-		fmt.Println("Chapter content appears empty or has no segments.")
-		return
-	}
-
-	// Validate startSegmentIndex
-	if startSegmentIndex < 0 || startSegmentIndex >= len(segments) {
-		fmt.Printf("Warning: Last read segment index (%d) is invalid for this chapter. Starting from segment 0.\n", startSegmentIndex)
-		startSegmentIndex = 0
-		if currentProgress.LastReadSegmentIndex != 0 {
-			currentProgress.LastReadSegmentIndex = 0
-			progressDirty = true
-			saveProgress() // Save corrected progress
-		}
+	text := activeText()
+	start, err := resolvePosition(text, spec, *fromBookmark)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
 	}
-
-	for segIdx := startSegmentIndex; segIdx < len(segments); segIdx++ {
-		segmentText := strings.TrimSpace(segments[segIdx])
-		if segmentText == "" {
-			continue
-		}
-
-		fmt.Printf("\n[Segment %d/%d]\n%s\n", segIdx+1, len(segments), segmentText)
-
-		doneChan, err := tts.SpeakAsync(segmentText)
-		if err != nil {
-			log.Printf("Error starting TTS for Ch %d, Seg %d: %v", targetChapterIndex+1, segIdx, err)
+	if spec == "" && *fromBookmark == "" && currentProgress().Status(activeNovel.ChapterHashes) == config.StatusFinished {
+		if _, err := text.stepSegments(start, 1); err != nil { // Nothing left after the last segment heard
+			fmt.Printf("You have finished '%s'. Use 'read 1' to listen to it again from the start.\n", activeNovel.Title)
 			return
 		}
-
-		// Update progress in memory *before* waiting
-		if currentProgress.LastReadChapterIndex != targetChapterIndex || currentProgress.LastReadSegmentIndex != segIdx {
-			currentProgress.LastReadChapterIndex = targetChapterIndex
-			currentProgress.LastReadSegmentIndex = segIdx
-			progressDirty = true // Mark progress dirty
-		}
-
-		fmt.Println("(Speaking...)")
-		err = <-doneChan
-
-		if err != nil {
-			log.Printf("Error during TTS for Ch %d, Seg %d: %v", targetChapterIndex+1, segIdx, err)
-			return
-		}
-		fmt.Println("(Segment finished)")
-		segmentsReadInSession++
-
-		// Periodic Save
-		if segmentsReadInSession%20 == 0 && progressDirty {
-			fmt.Printf("(Auto-saving progress after %d segments...)\n", segmentsReadInSession)
-			saveProgress() // Save progress data
-		}
-
-		if !cfg.AutoReadNext {
-			fmt.Println("Auto-next disabled. Stopping.")
-			return
-		}
-		if segIdx == len(segments)-1 {
-			break
-		}
 	}
-
-	// Auto-Next Chapter
-	if cfg.AutoReadNext {
-		fmt.Println("Chapter finished. Auto-reading next chapter...")
-		nextChapterIndexInternal := targetChapterIndex + 1
-		if nextChapterIndexInternal < len(activeNovel.Chapters) {
-			// handleRead will detect chapter change and save progress
-			handleRead([]string{strconv.Itoa(nextChapterIndexInternal + 1)})
-		} else {
-			fmt.Println("Reached the end of the novel.")
-		}
+	limits, err := limitsFor(text, start)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
 	}
+	readFrom(text, start, limits, spec == "" && *fromBookmark == "") // Recap only when resuming
 }
 
-func handleNext() {
+// handleSeek moves the active novel's reading position without reading.
+func handleSeek(args []string) {
+	fs := flag.NewFlagSet("seek", flag.ExitOnError)
+	fromBookmark := fs.String("from-bookmark", "", "move to the bookmark with this `name`")
+	spec := strings.Join(parseCommandFlags(fs, args), " ")
+	if spec == "" && *fromBookmark == "" {
+		log.Fatalf("Error: seek command requires a position: %s, or --from-bookmark <name>.", positionHelp)
+	}
+
 	if activeNovel == nil {
-		fmt.Println("No active novel.")
+		fmt.Println("No active novel selected. Use 'switch <novel>' first.")
 		return
 	}
 	loadActiveNovelChapters()
 	if len(activeNovel.Chapters) == 0 {
-		fmt.Println("Failed to load chapters for the active novel.")
+		fmt.Printf("Chapters not loaded for '%s'.\n", activeNovel.FilePath)
 		return
 	}
-	// Get current progress
-	currentProgress, ok := progressData[activeNovel.FilePath]
-	if !ok {
-		log.Printf("Error: Progress data not found for active novel %s", activeNovel.FilePath)
-		return
+
+	pos, err := resolvePosition(activeText(), spec, *fromBookmark)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
 	}
-	nextChapterIndex := currentProgress.LastReadChapterIndex + 1
-	if nextChapterIndex >= len(activeNovel.Chapters) {
-		fmt.Println("Already at the last chapter.")
-		return
+	progInfo := currentProgress()
+	if progInfo.LastReadChapterIndex != pos.Chapter || progInfo.LastReadSegmentIndex != pos.Segment {
+		progInfo.LastReadChapterIndex = pos.Chapter
+		progInfo.LastReadSegmentIndex = pos.Segment
+		progressDirty = true
 	}
-	handleRead([]string{strconv.Itoa(nextChapterIndex + 1)})
+	fmt.Printf("Moved to Chapter %d: %s, Segment %d. Use 'read' to continue there.\n",
+		pos.Chapter+1, activeNovel.Chapters[pos.Chapter].Title, pos.Segment)
 }
 
+// handleNext reads the next chapter of the active novel from its start.
+func handleNext() {
+	handleRead([]string{"+1"})
+}
+
+// handlePrev reads the previous chapter of the active novel from its start.
 func handlePrev() {
-	if activeNovel == nil {
-		fmt.Println("No active novel.")
-		return
-	}
-	loadActiveNovelChapters()
-	if len(activeNovel.Chapters) == 0 {
-		fmt.Println("Failed to load chapters for the active novel.")
-		return
-	}
-	// Get current progress
-	currentProgress, ok := progressData[activeNovel.FilePath]
-	if !ok {
-		log.Printf("Error: Progress data not found for active novel %s", activeNovel.FilePath)
-		return
-	}
-	prevChapterIndex := currentProgress.LastReadChapterIndex - 1
-	if prevChapterIndex < 0 {
-		fmt.Println("Already at the first chapter.")
-		return
-	}
-	handleRead([]string{strconv.Itoa(prevChapterIndex + 1)})
+	handleRead([]string{"-1"})
+}
+
+// handleNextSegment reads the active novel from the segment after the last read one.
+func handleNextSegment() {
+	handleRead([]string{"+1s"})
+}
+
+// handlePrevSegment reads the active novel from the segment before the last read one.
+func handlePrevSegment() {
+	handleRead([]string{"-1s"})
 }
 
 func handleWhere() {
@@ -566,8 +802,10 @@ func handleWhere() {
 	} else {
 		title = "(chapter index out of bounds)"
 	}
-	fmt.Printf("Active novel: %s\nLast read: Chapter %d (%s), Segment %d\n",
-		activeNovel.FilePath, lastChapIdx+1, title, lastSegIdx)
+	fmt.Printf("Active novel: %s (ID %s, %s)\nLast read: Chapter %d (%s), Segment %d\n",
+		activeNovel.Title, activeNovel.ID, activeNovel.FilePath, lastChapIdx+1, title, lastSegIdx)
+	fmt.Printf("Status: %s (%d of %d chapters completed)\n",
+		progInfo.Status(activeNovel.ChapterHashes), completedChapters(activeNovel, progInfo), len(activeNovel.ChapterTitles))
 }
 
 // --- Helper Functions ---
@@ -585,6 +823,58 @@ func getNovelsSorted() []*config.NovelInfo {
 	return sorted
 }
 
+// resolveNovel finds the novel a command argument refers to: its ID, its exact title,
+// or a part of its title or file name that matches no other novel. Matching ignores
+// case. An ambiguous query returns an error listing the candidates.
+func resolveNovel(query string) (*config.NovelInfo, error) {
+	q := strings.ToLower(strings.TrimSpace(query))
+	if q == "" {
+		return nil, fmt.Errorf("no novel given")
+	}
+	novels := getNovelsSorted()
+	for _, info := range novels {
+		if info.ID == q {
+			return info, nil
+		}
+	}
+
+	var exact, partial []*config.NovelInfo
+	for _, info := range novels {
+		title := strings.ToLower(info.Title)
+		switch {
+		case title == q:
+			exact = append(exact, info)
+		case strings.Contains(title, q), strings.Contains(strings.ToLower(filepath.Base(info.FilePath)), q):
+			partial = append(partial, info)
+		}
+	}
+	candidates := exact
+	if len(candidates) == 0 {
+		candidates = partial
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("no novel matches '%s'; use 'list' to see IDs and titles", query)
+	case 1:
+		return candidates[0], nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "'%s' matches %d novels; use one of the IDs:", query, len(candidates))
+	for _, info := range candidates {
+		fmt.Fprintf(&b, "\n  %s: %s [%s]", info.ID, info.Title, filepath.Base(info.FilePath))
+	}
+	return nil, errors.New(b.String())
+}
+
+// mustResolveNovel is resolveNovel for command arguments: failing to match exits.
+func mustResolveNovel(query string) *config.NovelInfo {
+	info, err := resolveNovel(query)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	return info
+}
+
 // loadActiveNovelMetadata loads only the metadata for the active novel.
 func loadActiveNovelMetadata() {
 	info, exists := cfg.Novels[cfg.ActiveNovelPath]
@@ -610,19 +900,22 @@ func loadActiveNovelChapters() {
 	}
 
 	fmt.Printf("Loading chapters for: %s\n", activeNovel.FilePath)
-	if _, err := os.Stat(activeNovel.FilePath); os.IsNotExist(err) {
-		log.Printf("Error: File for active novel not found: %s", activeNovel.FilePath)
+	size, modTime, err := novelSource(activeNovel).Stat()
+	if os.IsNotExist(err) && relocateFromRoots(activeNovel, cfg.LibraryRoots) {
+		size, modTime, err = novelSource(activeNovel).Stat()
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Printf("Error: File for active novel not found: %s", activeNovel.FilePath)
+			fmt.Println("If it was moved, use 'relocate' with its new path or add the folder it is in with 'config roots add <dir>'.")
+		} else {
+			log.Printf("Error reading file for active novel %s: %v", activeNovel.FilePath, err)
+		}
 		activeNovel.Chapters = nil // Clear potentially stale chapter data
 		return
 	}
 
-	regex, ok := regexMap[activeNovel.DetectedRegex]
-	if !ok {
-		log.Printf("Warning: Unknown regex name '%s' stored for novel. Falling back to markdown.", activeNovel.DetectedRegex)
-		regex = regexMap["markdown"]
-	}
-
-	parsedChapters, err := novel.ParseNovel(activeNovel.FilePath, regex)
+	parsedChapters, err := parseNovel(activeNovel)
 	if err != nil {
 		log.Printf("Error parsing novel %s: %v", activeNovel.FilePath, err)
 		activeNovel.Chapters = nil
@@ -630,24 +923,417 @@ func loadActiveNovelChapters() {
 	}
 
 	activeNovel.Chapters = parsedChapters
-	// Ensure ChapterTitles matches the loaded chapters
-	if len(activeNovel.ChapterTitles) != len(parsedChapters) {
-		log.Printf("Warning: Chapter title count mismatch after loading for %s. Rebuilding titles.", activeNovel.FilePath)
-		activeNovel.ChapterTitles = make([]string, len(parsedChapters))
-		for i, ch := range parsedChapters {
-			activeNovel.ChapterTitles[i] = ch.Title
+	hashes := novel.ChapterHashes(parsedChapters)
+	if size != activeNovel.FileSize || !modTime.Equal(activeNovel.ModTime) ||
+		!slices.Equal(hashes, activeNovel.ChapterHashes) {
+		reanchorProgress(activeNovel, parsedChapters, hashes)
+		activeNovel.FileSize = size
+		activeNovel.ModTime = modTime
+		activeNovel.Fingerprint = "" // Recomputed below
+		configDirty = true           // File state and possibly ChapterTitles changed
+	}
+	if activeNovel.Fingerprint == "" {
+		if fp, err := novel.Fingerprint(novelSource(activeNovel)); err == nil {
+			activeNovel.Fingerprint = fp
+			configDirty = true
 		}
-		configDirty = true // Mark config dirty as ChapterTitles changed
 	}
 
 	fmt.Printf("Loaded %d chapters.\n", len(activeNovel.Chapters))
 }
 
+// reanchorProgress updates a novel's chapter metadata after its file changed on disk.
+// The reading position is moved to wherever the last read chapter is now, matched by
+// content hash and title, and chapters added or changed since the last load from
+// there on are reported.
+func reanchorProgress(info *config.NovelInfo, chapters []novel.Chapter, hashes []string) {
+	oldTitles, oldHashes := info.ChapterTitles, info.ChapterHashes
+	titles := make([]string, len(chapters))
+	for i, ch := range chapters {
+		titles[i] = ch.Title
+	}
+	info.ChapterTitles = titles
+	info.ChapterHashes = hashes
+
+	// Novels saved before hashes were recorded can only be compared by title.
+	changed := !slices.Equal(oldTitles, titles)
+	if oldHashes != nil {
+		changed = !slices.Equal(oldHashes, hashes)
+	}
+	if !changed {
+		return
+	}
+	fmt.Printf("File changed since it was last loaded (%d -> %d chapters).\n", len(oldTitles), len(chapters))
+
+	if progInfo, ok := progressData[info.FilePath]; ok && len(oldTitles) > 0 {
+		oldIndex := progInfo.LastReadChapterIndex
+		newIndex, found := novel.RemapChapter(oldTitles, oldHashes, chapters, hashes, oldIndex)
+		switch {
+		case !found:
+			fmt.Printf("Warning: Could not find the last read chapter %d in the changed file. Position kept.\n", oldIndex+1)
+		case newIndex != oldIndex:
+			fmt.Printf("Last read chapter moved from %d to %d: %s\n", oldIndex+1, newIndex+1, titles[newIndex])
+			progInfo.LastReadChapterIndex = newIndex
+			progressDirty = true
+		}
+		if found && (oldIndex >= len(oldHashes) || oldHashes[oldIndex] != hashes[newIndex]) && progInfo.LastReadSegmentIndex != 0 {
+			fmt.Println("The last read chapter's text changed, starting it from the beginning.")
+			progInfo.LastReadSegmentIndex = 0
+			progressDirty = true
+		}
+	}
+
+	if oldHashes != nil {
+		// Only chapters still ahead matter; the reading position is already re-anchored.
+		from := 0
+		if progInfo, ok := progressData[info.FilePath]; ok {
+			from = min(progInfo.LastReadChapterIndex, len(chapters)-1)
+		}
+		added := slices.DeleteFunc(novel.NewChapters(oldHashes, hashes), func(idx int) bool { return idx < from })
+		if len(added) > 0 {
+			fmt.Printf("%d new or updated chapters from Chapter %d on, where reading continues:\n", len(added), from+1)
+			for i, idx := range added {
+				if i == maxListedNewChapters {
+					fmt.Printf("  ... and %d more\n", len(added)-i)
+					break
+				}
+				fmt.Printf("  %d: %s\n", idx+1, titles[idx])
+			}
+		}
+	}
+}
+
+// acquireReaderLock makes sure only one instance reads aloud. If another instance is
+// reading, the user may take over: it is stopped (saving its progress) and the latest
+// progress is reloaded. It reports whether this process may read.
+func acquireReaderLock() bool {
+	if readerLock != nil {
+		return true // Already held
+	}
+	lock, err := config.AcquireReaderLock(dataDir)
+	var running *config.ReaderRunningError
+	if errors.As(err, &running) {
+		fmt.Printf("Another instance (PID %d) is already reading.\n", running.PID)
+		if running.PID == 0 {
+			return false
+		}
+		fmt.Print("Stop it and take over? [y/N]: ")
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer := strings.ToLower(strings.TrimSpace(line)); answer != "y" && answer != "yes" {
+			fmt.Println("Not reading.")
+			return false
+		}
+		lock, err = config.TakeOverReaderLock(dataDir, running.PID, takeOverTimeout)
+		if err == nil {
+			reloadProgress()
+		}
+	}
+	if err != nil {
+		log.Printf("Error: Could not take the reader lock: %v", err)
+		return false
+	}
+	readerLock = lock
+	return true
+}
+
+// reloadProgress picks up the progress saved by an instance that was taken over.
+// Nothing has been read by this process yet, so the saved progress replaces its own.
+func reloadProgress() {
+	latest, err := store.LoadProgress()
+	if err != nil {
+		log.Fatalf("Error loading progress data: %v", err)
+	}
+	progressData = latest
+	progressDirty = false
+}
+
+// handleRelocate points novels whose files were moved to their new location. Without
+// arguments every missing novel is searched for by fingerprint in the library roots
+// and --root directories; with a novel and a path that novel is moved there, which
+// must hold the same content unless --force is given.
+func handleRelocate(args []string) {
+	fs := flag.NewFlagSet("relocate", flag.ExitOnError)
+	root := fs.String("root", "", "additional directory to search for moved novels")
+	force := fs.Bool("force", false, "relocate even if the new file's content differs")
+	args = parseCommandFlags(fs, args)
+	roots := cfg.LibraryRoots
+	if *root != "" {
+		dir, err := filepath.Abs(*root)
+		if err != nil {
+			log.Fatalf("Error getting absolute path for %s: %v", *root, err)
+		}
+		roots = append(slices.Clone(roots), dir)
+	}
+
+	if len(args) >= 2 {
+		info := mustResolveNovel(args[0])
+		newPath, err := filepath.Abs(args[1])
+		if err != nil {
+			log.Fatalf("Error getting absolute path for %s: %v", args[1], err)
+		}
+		fp, err := novel.Fingerprint(novel.Source{Path: newPath, Member: info.ArchiveMember})
+		if err != nil {
+			log.Fatalf("Error reading %s: %v", newPath, err)
+		}
+		if info.Fingerprint != "" && fp != info.Fingerprint && !*force {
+			log.Fatalf("Error: %s does not hold the same content as '%s'. Use --force to relocate anyway.", newPath, info.Title)
+		}
+		moveNovel(info, newPath)
+		if fp != info.Fingerprint {
+			info.Fingerprint = fp
+			info.FileSize = 0 // Re-check chapters when the novel is loaded next
+		}
+		return
+	}
+	if len(args) == 1 {
+		log.Fatal("Error: relocate requires both a novel and its new path, or no arguments to search the library roots.")
+	}
+
+	if len(roots) == 0 {
+		log.Fatal("Error: No library roots configured. Use 'config roots add <dir>' or pass --root <dir>.")
+	}
+	missing := 0
+	for _, info := range getNovelsSorted() {
+		if _, err := os.Stat(info.FilePath); !os.IsNotExist(err) {
+			continue
+		}
+		missing++
+		relocateFromRoots(info, roots)
+	}
+	if missing == 0 {
+		fmt.Println("All novels are where they were added.")
+	}
+}
+
+// relocateFromRoots searches roots for a missing novel by fingerprint and moves it to
+// the match. It reports whether the novel was relocated.
+func relocateFromRoots(info *config.NovelInfo, roots []string) bool {
+	if len(roots) == 0 {
+		return false
+	}
+	if info.Fingerprint == "" {
+		fmt.Printf("'%s' has no recorded fingerprint; use 'relocate %s <new path>'.\n", info.Title, info.ID)
+		return false
+	}
+	fmt.Printf("Searching library roots for '%s'...\n", info.Title)
+	dirName := ""
+	if info.SplitStrategy == novel.StrategyFiles && !novel.IsArchive(info.FilePath) {
+		dirName = filepath.Base(info.FilePath) // A directory of chapter files
+	}
+	matches, err := novel.FindMoved(roots, info.Fingerprint, info.FileSize, dirName)
+	if err != nil {
+		log.Printf("Error searching for '%s': %v", info.Title, err)
+		return false
+	}
+	matches = slices.DeleteFunc(matches, func(p string) bool {
+		_, inLibrary := cfg.Novels[p]
+		return inLibrary
+	})
+	switch len(matches) {
+	case 0:
+		fmt.Printf("Could not find '%s' in the library roots.\n", info.Title)
+		return false
+	case 1:
+		moveNovel(info, matches[0])
+		return true
+	}
+	fmt.Printf("Found several copies of '%s'; use 'relocate %s <path>' with one of:\n", info.Title, info.ID)
+	for _, m := range matches {
+		fmt.Printf("  %s\n", m)
+	}
+	return false
+}
+
+// moveNovel re-keys a novel's metadata and progress to its new path.
+func moveNovel(info *config.NovelInfo, newPath string) {
+	oldPath := info.FilePath
+	if newPath == oldPath {
+		fmt.Printf("'%s' is already at %s.\n", info.Title, newPath)
+		return
+	}
+	if _, exists := cfg.Novels[newPath]; exists {
+		log.Fatalf("Error: %s is already in the library as another novel.", newPath)
+	}
+	delete(cfg.Novels, oldPath)
+	info.FilePath = newPath
+	cfg.Novels[newPath] = info
+	if cfg.ActiveNovelPath == oldPath {
+		cfg.ActiveNovelPath = newPath
+	}
+	configDirty = true
+	if progInfo, ok := progressData[oldPath]; ok {
+		delete(progressData, oldPath)
+		progressData[newPath] = progInfo
+		progressDirty = true
+	}
+	fmt.Printf("Relocated '%s': %s -> %s\n", info.Title, oldPath, newPath)
+}
+
+// novelSource returns where a novel's text is read from.
+func novelSource(info *config.NovelInfo) novel.Source {
+	return novel.Source{Path: info.FilePath, Member: info.ArchiveMember}
+}
+
+// selectArchiveMember picks the novel file inside an archive. A single text file is
+// used directly; otherwise the members are listed and the user is asked to choose.
+// Choosing 0 reads every member as one chapter file each, like a directory.
+func selectArchiveMember(archivePath string) string {
+	members, err := novel.ArchiveMembers(archivePath)
+	if err != nil {
+		log.Fatalf("Error reading archive %s: %v", archivePath, err)
+	}
+	switch len(members) {
+	case 0:
+		log.Fatalf("Error: No text files found in %s", archivePath)
+	case 1:
+		fmt.Printf("Using archive member: %s\n", members[0])
+		return members[0]
+	}
+
+	fmt.Printf("%s contains %d text files:\n", filepath.Base(archivePath), len(members))
+	fmt.Println("  0: (all files, one chapter per file)")
+	for i, m := range members {
+		fmt.Printf("  %d: %s\n", i+1, m)
+	}
+	fmt.Print("Select a file (or pass --member): ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		log.Fatal("Error: No member selected. Use --member <name> to choose one.")
+	}
+	choice, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || choice < 0 || choice > len(members) {
+		log.Fatalf("Error: Invalid selection '%s'.", strings.TrimSpace(line))
+	}
+	if choice == 0 {
+		return ""
+	}
+	return members[choice-1]
+}
+
+// parseCommandFlags parses a command's flags, allowing them before, between or after
+// positional arguments, and returns the positional arguments. Negative numbers such as
+// the relative position -1 are positional arguments, not flags.
+func parseCommandFlags(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for len(args) > 0 {
+		if negativeNumber.MatchString(args[0]) {
+			positional = append(positional, args[0])
+			args = args[1:]
+			continue
+		}
+		end := slices.IndexFunc(args, negativeNumber.MatchString)
+		if end < 0 {
+			end = len(args)
+		}
+		fs.Parse(args[:end]) // ExitOnError flag sets exit on bad flags
+		if fs.NArg() == 0 {
+			args = args[end:]
+			continue
+		}
+		positional = append(positional, fs.Arg(0))
+		args = slices.Concat(fs.Args()[1:], args[end:])
+	}
+	return positional
+}
+
+// negativeNumber matches arguments like -1 or -10s.
+var negativeNumber = regexp.MustCompile(`^-\d+s?$`)
+
+// parseNovel splits a novel's file into chapters the way it was split when added,
+// then applies the novel's reflow mode.
+func parseNovel(info *config.NovelInfo) ([]novel.Chapter, error) {
+	chapters, err := novel.ParseNovel(novelSource(info), novelFormat(info))
+	if err != nil {
+		return nil, err
+	}
+	if novel.ReflowChapters(chapters, info.Reflow) && info.Reflow != novel.ReflowOn {
+		fmt.Println("Text looks hard-wrapped, joining lines into paragraphs. Use 'reflow off' to disable.")
+	}
+	return chapters, nil
+}
+
+// novelFormat rebuilds the split format recorded for a novel when it was added.
+func novelFormat(info *config.NovelInfo) *novel.Format {
+	format := &novel.Format{
+		Strategy:  info.SplitStrategy,
+		Name:      info.DetectedRegex,
+		ChunkSize: info.ChunkSize,
+	}
+	if format.Strategy == "" {
+		format.Strategy = novel.StrategyRegex // Novels added before split strategies existed
+	}
+	if format.Strategy == novel.StrategyRegex {
+		regex, ok := novel.FormatRegex(info.DetectedRegex)
+		if !ok {
+			log.Printf("Warning: Unknown regex name '%s' stored for novel. Falling back to markdown.", info.DetectedRegex)
+			regex = novel.ChapterRegexes["markdown"]
+		}
+		format.Regex = regex
+	}
+	return format
+}
+
+// enabledFilters returns the filter names enabled for a novel.
+func enabledFilters(info *config.NovelInfo) []string {
+	if info.Filters == nil {
+		return append([]string(nil), novel.DefaultFilters...)
+	}
+	return info.Filters
+}
+
+// findFilterRule returns the user filter rule with the given name, or nil.
+func findFilterRule(name string) *config.FilterRule {
+	for i := range cfg.FilterRules {
+		if cfg.FilterRules[i].Name == name {
+			return &cfg.FilterRules[i]
+		}
+	}
+	return nil
+}
+
+// filterPipeline builds the text filter pipeline enabled for a novel.
+// Unknown or invalid filters are skipped with a warning.
+func filterPipeline(info *config.NovelInfo) novel.Pipeline {
+	var pipeline novel.Pipeline
+	for _, name := range enabledFilters(info) {
+		if f, ok := novel.BuiltinFilters[name]; ok {
+			pipeline = append(pipeline, f)
+			continue
+		}
+		rule := findFilterRule(name)
+		if rule == nil {
+			log.Printf("Warning: Filter '%s' enabled for %s no longer exists.", name, info.FilePath)
+			continue
+		}
+		f, err := novel.NewRegexFilter(rule.Pattern, rule.Replace)
+		if err != nil {
+			log.Printf("Warning: Skipping filter '%s': %v", name, err)
+			continue
+		}
+		pipeline = append(pipeline, f)
+	}
+	return pipeline
+}
+
+// removeString returns list without any occurrence of s. A nil list stays nil.
+func removeString(list []string, s string) []string {
+	if list == nil {
+		return nil
+	}
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
 // saveConfig saves the main application configuration.
 func saveConfig() {
-	err := config.SaveConfig(configPath, cfg)
+	err := store.SaveConfig(cfg)
 	if err != nil {
-		log.Printf("Error saving config to %s: %v", configPath, err)
+		log.Printf("Error saving config to %s: %v", store.Location(), err)
 	} else {
 		fmt.Println("Configuration saved.")
 		configDirty = false
@@ -656,9 +1342,9 @@ func saveConfig() {
 
 // saveProgress saves the reading progress data.
 func saveProgress() {
-	err := config.SaveProgress(progressPath, progressData)
+	err := store.SaveProgress(progressData)
 	if err != nil {
-		log.Printf("Error saving progress to %s: %v", progressPath, err)
+		log.Printf("Error saving progress to %s: %v", store.Location(), err)
 	} else {
 		fmt.Println("Progress saved.")
 		progressDirty = false
@@ -0,0 +1,173 @@
+package novel
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Archive kinds supported as novel sources.
+const (
+	archiveZip   = "zip"
+	archiveTar   = "tar"
+	archiveTarGz = "tar.gz"
+	archiveGz    = "gz"
+)
+
+// archiveKind returns the archive kind of a file name by extension, or "".
+func archiveKind(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return archiveZip
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return archiveTarGz
+	case strings.HasSuffix(lower, ".tar"):
+		return archiveTar
+	case strings.HasSuffix(lower, ".gz"):
+		return archiveGz
+	}
+	return ""
+}
+
+// IsArchive reports whether the file is a supported archive (zip, tar, tar.gz or gz).
+func IsArchive(filePath string) bool {
+	return archiveKind(filePath) != ""
+}
+
+// ArchiveMembers lists the text files inside an archive, in natural order.
+func ArchiveMembers(archivePath string) ([]string, error) {
+	var names []string
+	err := walkArchive(archivePath, func(name string, _ io.Reader) (bool, error) {
+		names = append(names, name)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortNatural(names)
+	return names, nil
+}
+
+// readArchive reads text members of an archive: only member if it is set, otherwise
+// all of them in natural order.
+func readArchive(archivePath, member string) ([]File, error) {
+	var files []File
+	err := walkArchive(archivePath, func(name string, r io.Reader) (bool, error) {
+		if member != "" && name != member {
+			return false, nil
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return false, fmt.Errorf("reading %s from %s: %w", name, filepath.Base(archivePath), err)
+		}
+		files = append(files, File{Name: name, Content: string(data)})
+		return member != "", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if member != "" && len(files) == 0 {
+		return nil, fmt.Errorf("member %q not found in %s", member, filepath.Base(archivePath))
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no text files found in %s", filepath.Base(archivePath))
+	}
+	names := make([]string, len(files))
+	byName := make(map[string]File, len(files))
+	for i, f := range files {
+		names[i] = f.Name
+		byName[f.Name] = f
+	}
+	sortNatural(names)
+	for i, name := range names {
+		files[i] = byName[name]
+	}
+	return files, nil
+}
+
+// walkArchive calls fn for every text member of the archive until fn returns true.
+func walkArchive(archivePath string, fn func(name string, r io.Reader) (bool, error)) error {
+	kind := archiveKind(archivePath)
+	if kind == archiveZip {
+		zr, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() || !isTextMember(f.Name) {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			done, err := fn(f.Name, rc)
+			rc.Close()
+			if done || err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if kind == archiveTarGz || kind == archiveGz {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	switch kind {
+	case archiveGz:
+		// A plain .gz holds a single file named like the archive without ".gz".
+		_, err := fn(strings.TrimSuffix(filepath.Base(archivePath), filepath.Ext(archivePath)), r)
+		return err
+	case archiveTar, archiveTarGz:
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if hdr.Typeflag != tar.TypeReg || !isTextMember(hdr.Name) {
+				continue
+			}
+			done, err := fn(hdr.Name, tr)
+			if done || err != nil {
+				return err
+			}
+		}
+	}
+	return fmt.Errorf("unsupported archive: %s", archivePath)
+}
+
+// isTextMember reports whether an archive member is a visible text file.
+func isTextMember(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return false
+		}
+	}
+	return textExtensions[strings.ToLower(path.Ext(name))]
+}
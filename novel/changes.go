@@ -0,0 +1,110 @@
+package novel
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// ChapterHash returns a short hash of a chapter's content, used to recognise the
+// chapter after the file changed. Surrounding whitespace is ignored.
+func ChapterHash(ch Chapter) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(ch.Content)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ChapterHashes returns the ChapterHash of every chapter.
+func ChapterHashes(chapters []Chapter) []string {
+	hashes := make([]string, len(chapters))
+	for i, ch := range chapters {
+		hashes[i] = ChapterHash(ch)
+	}
+	return hashes
+}
+
+// RemapChapter finds where the chapter previously at oldIndex is in the changed
+// chapter list. Chapters are matched by content hash, then by title; when several
+// match, the one nearest to oldIndex wins. If the chapter itself is gone, the nearest
+// earlier chapter that can still be found is used as an anchor. oldHashes may be nil
+// for novels saved before hashes were recorded. It reports whether a match was found.
+func RemapChapter(oldTitles, oldHashes []string, chapters []Chapter, newHashes []string, oldIndex int) (int, bool) {
+	match := func(i int) (int, bool) {
+		title := ""
+		if i < len(oldTitles) {
+			title = normalizeTitle(oldTitles[i])
+		}
+		if i < len(oldHashes) {
+			// Chapters sharing a hash (like empty volume headings) are told apart by title.
+			idx, ok := nearest(i, len(chapters), func(j int) int {
+				if newHashes[j] != oldHashes[i] {
+					return 0
+				}
+				if title != "" && normalizeTitle(chapters[j].Title) == title {
+					return 2
+				}
+				return 1
+			})
+			if ok {
+				return idx, true
+			}
+		}
+		if title == "" {
+			return 0, false
+		}
+		return nearest(i, len(chapters), func(j int) int {
+			if normalizeTitle(chapters[j].Title) == title {
+				return 1
+			}
+			return 0
+		})
+	}
+
+	if idx, ok := match(oldIndex); ok {
+		return idx, true
+	}
+	for anchor := oldIndex - 1; anchor >= 0; anchor-- {
+		if idx, ok := match(anchor); ok {
+			idx += oldIndex - anchor
+			if idx >= len(chapters) {
+				idx = len(chapters) - 1
+			}
+			return idx, true
+		}
+	}
+	return 0, false
+}
+
+// nearest returns the index j < n with the highest positive score, preferring the
+// one closest to target among equal scores.
+func nearest(target, n int, score func(j int) int) (int, bool) {
+	best, bestScore := 0, 0
+	for j := 0; j < n; j++ {
+		sc := score(j)
+		if sc > bestScore || (sc > 0 && sc == bestScore && abs(j-target) < abs(best-target)) {
+			best, bestScore = j, sc
+		}
+	}
+	return best, bestScore > 0
+}
+
+// NewChapters returns the indexes of chapters whose content hash is not in oldHashes.
+func NewChapters(oldHashes, newHashes []string) []int {
+	known := make(map[string]bool, len(oldHashes))
+	for _, h := range oldHashes {
+		known[h] = true
+	}
+	var added []int
+	for i, h := range newHashes {
+		if !known[h] {
+			added = append(added, i)
+		}
+	}
+	return added
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
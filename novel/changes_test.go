@@ -0,0 +1,73 @@
+package novel
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// chapters builds chapters titled and filled by name; "=" in a name separates a
+// title from content that differs from it.
+func chapters(names ...string) []Chapter {
+	out := make([]Chapter, len(names))
+	for i, name := range names {
+		title, content, found := strings.Cut(name, "=")
+		if !found {
+			content = name
+		}
+		out[i] = Chapter{Title: title, Content: "Text of " + content}
+	}
+	return out
+}
+
+func TestRemapChapter(t *testing.T) {
+	old := chapters("1 Start", "2 Road", "3 City", "4 Night")
+	tests := []struct {
+		name     string
+		now      []Chapter
+		noHashes bool // The old chapters were saved before hashes were recorded
+		oldIndex int
+		want     int
+		wantOK   bool
+	}{
+		{"unchanged", old, false, 2, 2, true},
+		{"chapter inserted before", chapters("1 Start", "1.5 Detour", "2 Road", "3 City", "4 Night"), false, 2, 3, true},
+		{"chapter inserted after", chapters("1 Start", "2 Road", "3 City", "3.5 Detour", "4 Night"), false, 2, 2, true},
+		{"chapters appended", chapters("1 Start", "2 Road", "3 City", "4 Night", "5 Dawn", "6 Sea"), false, 3, 3, true},
+		{"chapter edited, found by title", chapters("1 Start", "2 Road", "3 City=3 City, revised", "4 Night"), false, 2, 2, true},
+		{"chapter retitled, found by content", chapters("1 Start", "2 Road", "3 The City=3 City", "4 Night"), false, 2, 2, true},
+		{"chapter removed, anchored on the one before", chapters("0 Foreword", "1 Start", "2 Road", "4 Night"), false, 2, 3, true},
+		{"saved before hashes", chapters("0 Foreword", "1 Start", "2 Road", "3 City", "4 Night"), true, 2, 3, true},
+		{"nothing left to anchor on", chapters("A", "B", "C"), false, 2, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldHashes := ChapterHashes(old)
+			if tt.noHashes {
+				oldHashes = nil
+			}
+			got, ok := RemapChapter(titlesOf(old), oldHashes, tt.now, ChapterHashes(tt.now), tt.oldIndex)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("RemapChapter(%d) = %d, %t; want %d, %t", tt.oldIndex, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRemapChapterSharedHashes(t *testing.T) {
+	// Volume headings without text share a hash; their titles tell them apart.
+	old := []Chapter{{Title: "Part One"}, {Title: "1 Start", Content: "a"}, {Title: "Part Two"}, {Title: "2 Road", Content: "b"}}
+	now := append([]Chapter{{Title: "Preface", Content: "p"}}, old...)
+	got, ok := RemapChapter(titlesOf(old), ChapterHashes(old), now, ChapterHashes(now), 2)
+	if got != 3 || !ok {
+		t.Errorf("RemapChapter(Part Two) = %d, %t; want 3, true", got, ok)
+	}
+}
+
+func TestNewChapters(t *testing.T) {
+	old := ChapterHashes(chapters("1 Start", "2 Road", "3 City"))
+	now := ChapterHashes(chapters("1 Start", "1.5 Detour", "2 Road", "3 City=3 City, revised", "4 Night"))
+	if got, want := NewChapters(old, now), []int{1, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("NewChapters() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,134 @@
+package novel
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Filter rewrites text before it is displayed or spoken.
+type Filter func(string) string
+
+// Pipeline applies filters in order.
+type Pipeline []Filter
+
+// Apply runs text through every filter of the pipeline.
+func (p Pipeline) Apply(text string) string {
+	for _, f := range p {
+		text = f(text)
+	}
+	return text
+}
+
+// BuiltinFilters holds the filters that can be enabled by name.
+var BuiltinFilters = map[string]Filter{
+	"markdown":   StripMarkdown,
+	"urls":       RemoveURLs,
+	"separators": RemoveSeparators,
+	"whitespace": NormalizeWhitespace,
+	"width":      NormalizeWidth,
+}
+
+// DefaultFilters are enabled for novels that haven't chosen their own filters.
+var DefaultFilters = []string{"markdown", "urls", "separators", "width", "whitespace"}
+
+// BuiltinFilterNames returns the names of the built-in filters, sorted.
+func BuiltinFilterNames() []string {
+	names := make([]string, 0, len(BuiltinFilters))
+	for name := range BuiltinFilters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewRegexFilter returns a filter replacing every match of pattern with replace.
+// An empty replacement deletes the matches. The replacement may refer to groups ($1).
+func NewRegexFilter(pattern, replace string) (Filter, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter pattern %q: %w", pattern, err)
+	}
+	return func(text string) string {
+		return re.ReplaceAllString(text, replace)
+	}, nil
+}
+
+// replacement is one regexp substitution of a built-in filter.
+type replacement struct {
+	re   *regexp.Regexp
+	with string
+}
+
+// applyReplacements runs the substitutions in order.
+func applyReplacements(text string, reps []replacement) string {
+	for _, r := range reps {
+		text = r.re.ReplaceAllString(text, r.with)
+	}
+	return text
+}
+
+var markdownReplacements = []replacement{
+	{regexp.MustCompile("(?m)^\\s*```.*$"), ""},                          // Code fences
+	{regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s+`), ""},                    // Headers
+	{regexp.MustCompile(`(?m)^\s*>\s?`), ""},                             // Blockquotes
+	{regexp.MustCompile(`(?m)^\s*(?:[-*+]|\d+[.)])\s+`), ""},             // List markers
+	{regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`), "$1"},                 // Images keep their alt text
+	{regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`), "$1"},                  // Links keep their text
+	{regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`), "$2"},       // Bold
+	{regexp.MustCompile(`(^|[^\w*])[*_](\S(?:[^*_]*?\S)?)[*_]`), "$1$2"}, // Italics
+	{regexp.MustCompile("`([^`]*)`"), "$1"},                              // Inline code
+	{regexp.MustCompile(`~~(.+?)~~`), "$1"},                              // Strikethrough
+	{regexp.MustCompile(`</?[a-zA-Z][^>]*>`), ""},                        // HTML tags
+}
+
+// StripMarkdown removes markdown syntax that TTS would otherwise read aloud.
+func StripMarkdown(text string) string {
+	return applyReplacements(text, markdownReplacements)
+}
+
+var urlPattern = regexp.MustCompile(`(?i)(?:https?://|www\.)\S+|\b[a-z0-9-]+(?:\.[a-z0-9-]+)*\.(?:com|net|org|cc|cn|tw|la|info|xyz|me|io|top|vip)\b(?:/\S*)?`)
+
+// RemoveURLs removes web addresses and bare site domains, as used in watermarks.
+func RemoveURLs(text string) string {
+	return urlPattern.ReplaceAllString(text, "")
+}
+
+var separatorLine = regexp.MustCompile(`(?m)^[\s\x{3000}]*([=\-*_~#+—－＝])(?:[\s\x{3000}]*[=\-*_~#+—－＝]){2,}[\s\x{3000}]*$`)
+
+// RemoveSeparators removes lines made only of repeated separator symbols, like "======".
+// Lines of dots, like "……" or "······", are kept: they are silent lines of dialogue.
+func RemoveSeparators(text string) string {
+	return separatorLine.ReplaceAllString(text, "")
+}
+
+var (
+	invisibleChars = strings.NewReplacer("\u200b", "", "\u200c", "", "\u200d", "", "\ufeff", "")
+	spaceRun       = regexp.MustCompile(`[ \t\x{00a0}\x{3000}]+`)
+)
+
+// NormalizeWhitespace removes invisible characters, collapses runs of spaces
+// (including full-width and non-breaking ones) and trims every line.
+func NormalizeWhitespace(text string) string {
+	text = invisibleChars.Replace(text)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(spaceRun.ReplaceAllString(line, " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// NormalizeWidth converts full-width letters, digits and spaces to their half-width
+// forms, so "ＡＢＣ１２３" is read as "ABC123". Full-width punctuation is kept.
+func NormalizeWidth(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '　':
+			return ' '
+		case r >= '０' && r <= '９', r >= 'Ａ' && r <= 'Ｚ', r >= 'ａ' && r <= 'ｚ':
+			return r - 0xFEE0
+		}
+		return r
+	}, text)
+}
@@ -0,0 +1,113 @@
+package novel
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fingerprintSize is how much of a novel is hashed for its fingerprint.
+const fingerprintSize = 64 * 1024
+
+// Fingerprint identifies a novel's content independently of where it is stored: a
+// hash of its first 64KB. For a directory that is the start of its text files in
+// natural order; files and archives are hashed as stored.
+func Fingerprint(src Source) (string, error) {
+	info, err := os.Stat(src.Path)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if !info.IsDir() {
+		file, err := os.Open(src.Path)
+		if err != nil {
+			return "", err
+		}
+		defer file.Close()
+		if _, err := io.CopyN(h, file, fingerprintSize); err != nil && err != io.EOF {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)[:8]), nil
+	}
+
+	names, err := textFilesIn(src.Path)
+	if err != nil {
+		return "", err
+	}
+	remaining := int64(fingerprintSize)
+	for _, name := range names {
+		if remaining <= 0 {
+			break
+		}
+		file, err := os.Open(filepath.Join(src.Path, name))
+		if err != nil {
+			return "", err
+		}
+		n, err := io.CopyN(h, file, remaining)
+		file.Close()
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		remaining -= n
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8]), nil
+}
+
+// FindMoved searches the directory trees below roots for a novel with the given
+// fingerprint and returns the paths of all matches. Only text files and archives are
+// hashed; their size may differ from the recorded one, since serialized novels grow
+// after the fingerprinted start. When several files match, those of the recorded size
+// (when known, i.e. > 0) are preferred. Directory novels are only looked for under
+// their original name, dirName, since hashing every directory would be slow. Hidden
+// files and directories are skipped.
+func FindMoved(roots []string, fingerprint string, size int64, dirName string) ([]string, error) {
+	var matches, sameSize []string
+	seen := make(map[string]bool)
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if p == root {
+					return err
+				}
+				return nil // Skip unreadable entries below the root
+			}
+			if p != root && strings.HasPrefix(d.Name(), ".") {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() != (dirName != "") || seen[p] {
+				return nil
+			}
+			if d.IsDir() {
+				if d.Name() != dirName {
+					return nil
+				}
+			} else {
+				if !d.Type().IsRegular() || (!textExtensions[strings.ToLower(filepath.Ext(p))] && !IsArchive(p)) {
+					return nil
+				}
+			}
+			seen[p] = true
+			if fp, err := Fingerprint(Source{Path: p}); err == nil && fp == fingerprint {
+				matches = append(matches, p)
+				if info, err := d.Info(); err == nil && !d.IsDir() && size > 0 && info.Size() == size {
+					sameSize = append(sameSize, p)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(matches) > 1 && len(sameSize) > 0 {
+		return sameSize, nil
+	}
+	return matches, nil
+}
@@ -0,0 +1,96 @@
+package novel
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindMoved(t *testing.T) {
+	// Longer than the fingerprinted start, so appending chapters keeps the fingerprint.
+	text := strings.Repeat("第一章 出发\n\n天下大势，分久必合，合久必分。\n\n", 3000)
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "old", "novel.txt"), text)
+	fp, err := Fingerprint(Source{Path: filepath.Join(dir, "old", "novel.txt")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := int64(len(text))
+	os.RemoveAll(filepath.Join(dir, "old"))
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name:  "moved",
+			files: map[string]string{"books/novel.txt": text},
+			want:  []string{"books/novel.txt"},
+		},
+		{
+			name:  "moved and grown",
+			files: map[string]string{"books/renamed.txt": text + "第二章 山路\n\n新的一章。\n"},
+			want:  []string{"books/renamed.txt"},
+		},
+		{
+			name: "several copies: the one of the recorded size wins",
+			files: map[string]string{
+				"a/novel.txt": text + "第二章 山路\n",
+				"b/novel.txt": text,
+			},
+			want: []string{"b/novel.txt"},
+		},
+		{
+			name: "several grown copies are all returned",
+			files: map[string]string{
+				"a/novel.txt": text + "第二章 山路\n",
+				"b/novel.txt": text + "第二章 山路\n\n第三章 入城\n",
+			},
+			want: []string{"a/novel.txt", "b/novel.txt"},
+		},
+		{
+			name: "other text and hidden files are skipped",
+			files: map[string]string{
+				"other.txt":        "第一章 别的书\n",
+				".trash/novel.txt": text,
+				"notes/novel.jpg":  text,
+				"books/.novel.txt": text,
+			},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tt.files {
+				writeFile(t, filepath.Join(root, name), content)
+			}
+			matches, err := FindMoved([]string{root}, fp, size, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, m := range matches {
+				rel, _ := filepath.Rel(root, m)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("FindMoved() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
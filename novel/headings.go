@@ -0,0 +1,104 @@
+package novel
+
+import (
+	"regexp"
+	"strings"
+)
+
+// HeadingKind classifies what a heading line introduces.
+type HeadingKind string
+
+const (
+	KindChapter HeadingKind = "chapter" // Regular numbered chapters (第N章, Chapter IV, 제N화)
+	KindVolume  HeadingKind = "volume"  // Volumes, parts and books grouping chapters (第N卷, Part Two)
+	KindExtra   HeadingKind = "extra"   // Unnumbered special sections (楔子, Prologue, 番外)
+)
+
+// HeadingRule describes one recognisable chapter heading pattern.
+type HeadingRule struct {
+	Name     string         // Unique rule name, stored in NovelInfo.DetectedRegex
+	Language string         // "zh", "ja", "ko", "en" or "any"
+	Kind     HeadingKind    // What the heading introduces
+	Pattern  *regexp.Regexp // Matched against a single line
+}
+
+// Fragments shared by several rules.
+const (
+	lead      = `^[\s\x{3000}]*` // Leading ASCII and full-width spaces
+	cjkNumber = `[一二三四五六七八九十百千万萬零〇两兩壹贰貳叁參肆伍陆陸柒捌玖拾佰仟\d０-９]+`
+	enNumber  = `(?:\d+|[ivxlcdm]+|` +
+		`(?:twenty|thirty|forty|fifty|sixty|seventy|eighty|ninety)(?:[\s-]+(?:one|two|three|four|five|six|seven|eight|nine))?|` +
+		`one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve|thirteen|fourteen|fifteen|sixteen|seventeen|eighteen|nineteen|` +
+		`one hundred)`
+	shortTail = `(?:[\s\x{3000}:：.·\-—_、].{0,40})?$` // Optional short subtitle after a keyword
+)
+
+// HeadingRules is the built-in heading vocabulary, in priority order.
+// DetectFormat scores every rule against a sample and combines the winners.
+var HeadingRules = []HeadingRule{
+	{"zh-chapter", "zh", KindChapter, regexp.MustCompile(lead + `第[\s\x{3000}]*` + cjkNumber + `[\s\x{3000}]*[章节節回囘].*$`)},
+	{"zh-volume", "zh", KindVolume, regexp.MustCompile(lead + `第[\s\x{3000}]*` + cjkNumber + `[\s\x{3000}]*[卷部集篇].*$`)},
+	{"zh-extra", "zh", KindExtra, regexp.MustCompile(lead + `(?:序章|序幕|序言|楔子|引子|前言|尾声|尾聲|后记|後記|终章|終章|大结局|大結局|完本感言|番外[篇\d一二三四五六七八九十]*)` + shortTail)},
+	{"ja-chapter", "ja", KindChapter, regexp.MustCompile(lead + `第[\s\x{3000}]*` + cjkNumber + `[\s\x{3000}]*[話话幕].*$`)},
+	{"ja-extra", "ja", KindExtra, regexp.MustCompile(lead + `(?:プロローグ|エピローグ|幕間|閑話|間章|序章|終章)` + shortTail)},
+	{"ko-chapter", "ko", KindChapter, regexp.MustCompile(lead + `제[\s\x{3000}]*\d+[\s\x{3000}]*[화장].*$`)},
+	{"ko-extra", "ko", KindExtra, regexp.MustCompile(lead + `(?:프롤로그|에필로그|외전|서장|종장)` + shortTail)},
+	{"en-chapter", "en", KindChapter, regexp.MustCompile(`(?i)` + lead + `chapter\s+` + enNumber + `\b.*$`)},
+	{"en-volume", "en", KindVolume, regexp.MustCompile(`(?i)` + lead + `(?:part|book|volume)\s+` + enNumber + `\b.*$`)},
+	{"en-extra", "en", KindExtra, regexp.MustCompile(`(?i)` + lead + `(?:prologue|epilogue|interlude|afterword|foreword|preface)\b` + shortTail)},
+	{"markdown", "any", KindChapter, regexp.MustCompile(`^\s*#{1,6}\s+.*$`)},
+}
+
+// formatSeparator joins rule names into a single stored format name.
+const formatSeparator = "+"
+
+// findRule returns the built-in rule with the given name.
+func findRule(name string) (HeadingRule, bool) {
+	for _, rule := range HeadingRules {
+		if rule.Name == name {
+			return rule, true
+		}
+	}
+	return HeadingRule{}, false
+}
+
+// combineRules builds one regex matching any of the given rules.
+func combineRules(rules []HeadingRule) *regexp.Regexp {
+	if len(rules) == 1 {
+		return rules[0].Pattern
+	}
+	parts := make([]string, len(rules))
+	for i, rule := range rules {
+		parts[i] = "(?:" + rule.Pattern.String() + ")"
+	}
+	return regexp.MustCompile(strings.Join(parts, "|"))
+}
+
+// FormatRegex resolves a stored format name back into its regex.
+// The name is either a legacy key of ChapterRegexes ("chinese", "english", "markdown")
+// or rule names from HeadingRules joined with "+", as returned by DetectFormat.
+func FormatRegex(name string) (*regexp.Regexp, bool) {
+	if re, ok := ChapterRegexes[name]; ok {
+		return re, true
+	}
+	var rules []HeadingRule
+	for _, ruleName := range strings.Split(name, formatSeparator) {
+		rule, ok := findRule(ruleName)
+		if !ok {
+			return nil, false
+		}
+		rules = append(rules, rule)
+	}
+	return combineRules(rules), true
+}
+
+// TitleKind classifies a chapter title by the first built-in rule it matches, for
+// showing volumes as groups of chapters. Titles no rule matches are chapters.
+func TitleKind(title string) HeadingKind {
+	for _, rule := range HeadingRules {
+		if rule.Pattern.MatchString(title) {
+			return rule.Kind
+		}
+	}
+	return KindChapter
+}
@@ -0,0 +1,172 @@
+package novel
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxTitleRunes      = 30  // Longest line still considered a title
+	minHeuristicTitles = 3   // Fewest title-like lines needed to trust the heuristic
+	minTitleBodyRunes  = 200 // Shortest body allowed between two heuristic titles
+	maxTitleLineRatio  = 20  // Unnumbered titles may be at most 1 in this many non-blank lines
+)
+
+// terminalPunctuation ends sentences, which titles normally don't.
+const terminalPunctuation = "。！？!?.,，;；:：、…—”\"'’」』"
+
+// openingQuotes start dialogue lines, which are often short and isolated too.
+const openingQuotes = "“\"'‘「『"
+
+// numberedTitle matches titles that start with a bare number: "12", "一、出发", "IV. The Road".
+var numberedTitle = regexp.MustCompile(`^(?:[\d０-９]+|[一二三四五六七八九十百千零〇]+|[IVXLC]+)(?:[\s\x{3000}.、:：\-—]|$)`)
+
+// isBlank reports whether the line at index i is missing or empty.
+func isBlank(lines []string, i int) bool {
+	return i < 0 || i >= len(lines) || strings.TrimSpace(lines[i]) == ""
+}
+
+// isTitleLike reports whether line i is short, isolated by blank lines and free of
+// sentence punctuation, i.e. laid out like a chapter title.
+func isTitleLike(lines []string, i int) bool {
+	t := strings.TrimSpace(lines[i])
+	if t == "" || utf8.RuneCountInString(t) > maxTitleRunes {
+		return false
+	}
+	if !isBlank(lines, i-1) || !isBlank(lines, i+1) {
+		return false
+	}
+	first, _ := utf8.DecodeRuneInString(t)
+	last, _ := utf8.DecodeLastRuneInString(t)
+	return !strings.ContainsRune(terminalPunctuation, last) && !strings.ContainsRune(openingQuotes, first)
+}
+
+// findTitleLines returns the indexes of lines that look like chapter titles when the
+// text has no recognisable heading vocabulary. Numbered titles are preferred since
+// they repeat a layout; otherwise every title-like line is used if such lines are rare
+// enough not to be ordinary short paragraphs. An embedded table of contents is split
+// off and returned separately (see splitTOC), then titles with too little body text
+// between them are merged away.
+func findTitleLines(lines []string) (titles []int, toc []string) {
+	var candidates, numbered []int
+	nonBlank := 0
+	for i := range lines {
+		if isBlank(lines, i) {
+			continue
+		}
+		nonBlank++
+		if !isTitleLike(lines, i) {
+			continue
+		}
+		candidates = append(candidates, i)
+		if numberedTitle.MatchString(strings.TrimSpace(lines[i])) {
+			numbered = append(numbered, i)
+		}
+	}
+
+	titles = numbered
+	if len(numbered) < minHeuristicTitles {
+		if len(candidates)*maxTitleLineRatio > nonBlank {
+			return nil, nil
+		}
+		titles = candidates
+	}
+	titles, toc = splitTOC(lines, titles)
+
+	var accepted []int
+	for _, idx := range titles {
+		if len(accepted) > 0 && bodyRunes(lines, accepted[len(accepted)-1]+1, idx) < minTitleBodyRunes {
+			continue
+		}
+		accepted = append(accepted, idx)
+	}
+	if len(accepted) < minHeuristicTitles {
+		return nil, nil
+	}
+	return accepted, toc
+}
+
+// bodyRunes counts the non-space runes in lines[from:to].
+func bodyRunes(lines []string, from, to int) int {
+	n := 0
+	for _, line := range lines[from:to] {
+		n += utf8.RuneCountInString(strings.TrimSpace(line))
+	}
+	return n
+}
+
+// splitFixed splits text into pseudo-chapters of roughly size runes, cutting only at
+// paragraph boundaries. Paragraphs are separated by blank lines; text without any
+// blank lines is treated as one paragraph per line.
+func splitFixed(lines []string, size int) []Chapter {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	paragraphs := paragraphsOf(lines)
+
+	var chapters []Chapter
+	var current []string
+	currentRunes := 0
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		chapters = append(chapters, Chapter{
+			Title:   fmt.Sprintf("Section %d", len(chapters)+1),
+			Content: strings.Join(current, "\n\n"),
+		})
+		current = nil
+		currentRunes = 0
+	}
+	for _, p := range paragraphs {
+		current = append(current, p)
+		currentRunes += utf8.RuneCountInString(p)
+		if currentRunes >= size {
+			flush()
+		}
+	}
+	flush()
+	return chapters
+}
+
+// paragraphsOf groups lines into trimmed paragraphs.
+func paragraphsOf(lines []string) []string {
+	// Only blank lines between paragraphs count, not leading or trailing ones.
+	hasBlank := false
+	seenText, pendingBlank := false, false
+	for i := range lines {
+		if isBlank(lines, i) {
+			pendingBlank = seenText
+			continue
+		}
+		if pendingBlank {
+			hasBlank = true
+			break
+		}
+		seenText = true
+	}
+
+	var paragraphs []string
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			paragraphs = append(paragraphs, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+	for _, line := range lines {
+		t := strings.TrimSpace(line)
+		if t == "" {
+			flush()
+			continue
+		}
+		current = append(current, t)
+		if !hasBlank {
+			flush()
+		}
+	}
+	flush()
+	return paragraphs
+}
@@ -1,10 +1,8 @@
 package novel
 
 import (
-	"bufio"
 	"errors"
 	"fmt" // Ensure fmt is imported
-	"io"
 	"os"
 	"regexp"
 	"strings"
@@ -16,8 +14,9 @@ type Chapter struct {
 	Content string
 }
 
-// ChapterRegexes holds the candidate regular expressions for chapter detection.
-// It is exported so it can be potentially used or referenced by other packages (like main).
+// ChapterRegexes holds the legacy named regular expressions for chapter detection.
+// Novels added before HeadingRules existed store one of these names, so they are kept
+// for FormatRegex to resolve. New detections use HeadingRules instead.
 var ChapterRegexes = map[string]*regexp.Regexp{
 	"chinese":  regexp.MustCompile(`^\s*第\s*[一二三四五六七八九十百千万零〇\d]+\s*[章卷节回].*$`),
 	"english":  regexp.MustCompile(`^\s*Chapter\s+\d+.*$`),
@@ -26,129 +25,205 @@ var ChapterRegexes = map[string]*regexp.Regexp{
 
 const detectBufferSize = 1 * 1024 * 1024 // 1MB for format detection
 
-// DetectFormat attempts to automatically detect the chapter title format.
-func DetectFormat(filePath string) (*regexp.Regexp, error) {
-	file, err := os.Open(filePath)
+// minChapterScore is the number of matches a heading rule needs to be trusted.
+const minChapterScore = 2
+
+// minHeadingSpacing is the number of non-blank lines that must separate two matches
+// of a rule for both to count towards its score, so a table of contents or a cluster
+// of heading-like lines counts once.
+const minHeadingSpacing = 2
+
+// Split strategies, recorded in NovelInfo.SplitStrategy so reloading splits the same way.
+const (
+	StrategyRegex     = "regex"     // Split on lines matching a heading regex
+	StrategyHeuristic = "heuristic" // Split on short, isolated, title-like lines
+	StrategyFixed     = "fixed"     // Split into fixed-size pseudo-chapters at paragraph boundaries
+	StrategyFiles     = "files"     // One chapter per file of a novel directory
+)
+
+// DefaultChunkSize is the pseudo-chapter size in runes used by StrategyFixed.
+const DefaultChunkSize = 5000
+
+// Format describes how a novel's text is split into chapters.
+type Format struct {
+	Strategy  string         // One of the Strategy constants
+	Name      string         // Heading format name for StrategyRegex (see FormatRegex)
+	Regex     *regexp.Regexp // Heading regex for StrategyRegex
+	ChunkSize int            // Pseudo-chapter size in runes for StrategyFixed
+}
+
+// DetectFormat attempts to automatically detect how the novel should be split.
+// It first scores the heading rules against the first 1MB of the text (see
+// detectHeadings). If no rule is convincing it looks for title-like lines, and
+// as a last resort falls back to fixed-size pseudo-chapters. For a directory or
+// archive of chapter files, see detectFilesFormat.
+func DetectFormat(src Source) (*Format, error) {
+	info, err := os.Stat(src.Path)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	if info.IsDir() || IsArchive(src.Path) {
+		files, err := src.Files()
+		if err != nil {
+			return nil, err
+		}
+		return detectFilesFormat(files)
+	}
 
-	reader := bufio.NewReader(file)
-	buffer := make([]byte, detectBufferSize) // Read up to 1MB
-	n, err := io.ReadFull(reader, buffer)
-	// io.ReadFull returns io.ErrUnexpectedEOF if less than buffer size is read, which is expected for smaller files.
-	// It returns io.EOF only if 0 bytes were read.
-	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+	sample, err := readSample(src.Path)
+	if err != nil {
 		return nil, err
 	}
-	contentSample := string(buffer[:n])
+	return detectTextFormat(splitLines(sample)), nil
+}
+
+// detectTextFormat picks the split format for a single text sample.
+func detectTextFormat(lines []string) *Format {
+	format, err := detectHeadings(lines)
+	if err == nil {
+		return format
+	}
+	fmt.Printf("Warning: %v.\n", err)
+
+	if titles, _ := findTitleLines(lines); len(titles) >= minHeuristicTitles {
+		fmt.Printf("Detected %d title-like lines, splitting heuristically.\n", len(titles))
+		return &Format{Strategy: StrategyHeuristic}
+	}
 
+	fmt.Printf("Warning: No chapter headings found, splitting into sections of about %d characters.\n", DefaultChunkSize)
+	return &Format{Strategy: StrategyFixed, ChunkSize: DefaultChunkSize}
+}
+
+// minHeadingsPerFile is the average number of headings per file above which a
+// directory's files are parsed for chapters instead of becoming one chapter each.
+const minHeadingsPerFile = 1.5
+
+// detectFilesFormat picks the split format for the files of a directory or archive. Files
+// holding several chapter headings each are joined and split like a single file;
+// otherwise every file becomes one chapter (StrategyFiles).
+func detectFilesFormat(files []File) (*Format, error) {
+	if len(files) == 0 {
+		return nil, errors.New("no text files found")
+	}
+	if len(files) > 1 {
+		format, err := detectHeadings(splitLines(joinFiles(files, detectBufferSize)))
+		if err == nil {
+			headings := len(matchingLines(splitLines(joinFiles(files, 0)), format.Regex))
+			if float64(headings) >= minHeadingsPerFile*float64(len(files)) {
+				return format, nil
+			}
+		}
+		fmt.Printf("Found %d files, using one chapter per file.\n", len(files))
+		return &Format{Strategy: StrategyFiles}, nil
+	}
+	return detectTextFormat(splitLines(joinFiles(files, detectBufferSize))), nil
+}
+
+// detectHeadings scores every rule in HeadingRules against the sample lines: the
+// number of matches at least minHeadingSpacing lines apart. The best scoring chapter
+// rule is combined with any volume or extra rules that score as high as a chapter
+// rule must, so a book using both 楔子 and 第N章 is split on both, while a stray
+// "第一部分" line in the text is not mistaken for a heading.
+func detectHeadings(lines []string) (*Format, error) {
 	scores := make(map[string]int)
-	// Use strings.Split is simpler for a fixed buffer than a scanner
-	lines := strings.Split(contentSample, "\n")
+	since := make(map[string]int) // Non-blank lines since each rule's last counted match
 	for _, line := range lines {
 		trimmedLine := strings.TrimSpace(line) // Trim whitespace for matching
 		if trimmedLine == "" {
 			continue
 		}
-		for format, re := range ChapterRegexes { // Use exported variable
-			if re.MatchString(trimmedLine) { // Match against trimmed line
-				scores[format]++
-				// Optional: break inner loop if one format matches? Assumes titles are unique.
-				// break
+		for _, rule := range HeadingRules {
+			if !rule.Pattern.MatchString(trimmedLine) {
+				since[rule.Name]++
+				continue
+			}
+			if scores[rule.Name] == 0 || since[rule.Name] >= minHeadingSpacing {
+				scores[rule.Name]++
+				since[rule.Name] = 0
 			}
 		}
 	}
-	// Scanner error check is not needed when using strings.Split
-
-	bestFormat := ""
-	// Start with a minimum score threshold to avoid spurious matches on random lines
-	maxScore := 1 // Require at least 2 matches to be considered
-	for format, score := range scores {
-		if score > maxScore {
-			maxScore = score
-			bestFormat = format
-		} else if score == maxScore && score > 1 {
-			// Handle ties? For now, first one wins or could prioritize (e.g. markdown)
-			// Or maybe require a significantly higher score?
+
+	// Pick the best chapter rule; earlier rules win ties.
+	var best HeadingRule
+	maxScore := minChapterScore - 1
+	for _, rule := range HeadingRules {
+		if rule.Kind == KindChapter && scores[rule.Name] > maxScore {
+			maxScore = scores[rule.Name]
+			best = rule
 		}
 	}
 
-	if bestFormat == "" {
-		// Default or fallback if no clear winner
-		// Let's prioritize markdown if score is low, otherwise return error?
-		if maxScore <= 1 { // If only 0 or 1 match found for the best format
-			// Check if markdown has at least one match, prefer it as default
-			if scores["markdown"] >= 1 {
-				fmt.Println("Warning: Low confidence in format detection, defaulting to markdown.")
-				return ChapterRegexes["markdown"], nil
-			}
-			// If even markdown doesn't match, return error
-			return nil, errors.New("could not reliably detect chapter format, few or no chapter titles found in sample")
+	if best.Name == "" {
+		// Check if markdown has at least one match, prefer it as default
+		if scores["markdown"] >= 1 {
+			fmt.Println("Warning: Low confidence in format detection, defaulting to markdown.")
+			return &Format{Strategy: StrategyRegex, Name: "markdown", Regex: ChapterRegexes["markdown"]}, nil
 		}
-		// If a best format was found (score > 1)
-		fmt.Printf("Detected format '%s' with score %d\n", bestFormat, maxScore)
-		return ChapterRegexes[bestFormat], nil
-	}
-	// This part should not be reachable if the logic above is correct,
-	// but the compiler needs a return path.
-	// If bestFormat is "", it means maxScore <= 1. The logic inside the if block handles this.
-	// If somehow we exit the loop and bestFormat is set, we return it.
-	// This path indicates successful detection.
-	return ChapterRegexes[bestFormat], nil
-}
-
-// ParseNovel reads a novel file and splits it into chapters based on the provided regex.
-func ParseNovel(filePath string, chapterRegex *regexp.Regexp) ([]Chapter, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return nil, err
+		return nil, errors.New("could not reliably detect chapter format, few or no chapter titles found in sample")
 	}
-	defer file.Close()
-
-	var chapters []Chapter
-	var currentContent strings.Builder
-	var currentTitle string
-
-	scanner := bufio.NewScanner(file)
-	firstChapter := true
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		if chapterRegex.MatchString(line) {
-			// Found a new chapter title
-			if !firstChapter {
-				// Save the previous chapter's content
-				chapters = append(chapters, Chapter{
-					Title:   strings.TrimSpace(currentTitle),
-					Content: strings.TrimSpace(currentContent.String()),
-				})
+	// Markdown headers already cover every heading kind, so only combine the text rules.
+	selected := []HeadingRule{best}
+	if best.Language != "any" {
+		for _, rule := range HeadingRules {
+			if rule.Name == best.Name || rule.Language == "any" {
+				continue
 			}
-			// Start new chapter
-			currentTitle = line
-			currentContent.Reset()
-			firstChapter = false
-		} else {
-			// Append line to current chapter content
-			if !firstChapter { // Don't add content before the first chapter title
-				currentContent.WriteString(line)
-				currentContent.WriteString("\n") // Add newline back
+			if scores[rule.Name] >= minChapterScore {
+				selected = append(selected, rule)
 			}
 		}
 	}
 
-	// Add the last chapter
-	if !firstChapter {
-		chapters = append(chapters, Chapter{
-			Title:   strings.TrimSpace(currentTitle),
-			Content: strings.TrimSpace(currentContent.String()),
-		})
+	names := make([]string, len(selected))
+	for i, rule := range selected {
+		names[i] = rule.Name
+		fmt.Printf("Detected %s headings '%s' (%s) with score %d\n", rule.Kind, rule.Name, rule.Language, scores[rule.Name])
 	}
+	return &Format{
+		Strategy: StrategyRegex,
+		Name:     strings.Join(names, formatSeparator),
+		Regex:    combineRules(selected),
+	}, nil
+}
 
-	if err := scanner.Err(); err != nil {
+// ParseNovel reads a novel and splits it into chapters using the given format.
+// The files of a directory or archive are joined in natural order, unless the
+// format makes each of them a chapter. Project Gutenberg header and license blocks
+// are removed first.
+func ParseNovel(src Source, format *Format) ([]Chapter, error) {
+	files, err := src.Files()
+	if err != nil {
 		return nil, err
 	}
+	if format.Strategy == StrategyFiles {
+		chapters := make([]Chapter, len(files))
+		for i, f := range files {
+			chapters[i] = fileChapter(f)
+		}
+		if len(chapters) == 0 {
+			return nil, errors.New("no text files found")
+		}
+		return chapters, nil
+	}
+	lines := splitLines(StripGutenberg(joinFiles(files, 0)))
+
+	var chapters []Chapter
+	switch format.Strategy {
+	case StrategyRegex, "":
+		titles, toc := splitTOC(lines, matchingLines(lines, format.Regex))
+		chapters = splitAtTitles(lines, titles)
+		validateTOC(toc, chapters)
+	case StrategyHeuristic:
+		titles, toc := findTitleLines(lines)
+		chapters = splitAtTitles(lines, titles)
+		validateTOC(toc, chapters)
+	case StrategyFixed:
+		chapters = splitFixed(lines, format.ChunkSize)
+	default:
+		return nil, fmt.Errorf("unknown split strategy %q", format.Strategy)
+	}
 
 	if len(chapters) == 0 {
 		return nil, errors.New("no chapters found using the detected format")
@@ -156,3 +231,40 @@ func ParseNovel(filePath string, chapterRegex *regexp.Regexp) ([]Chapter, error)
 
 	return chapters, nil
 }
+
+// splitLines splits text into lines, dropping Windows line endings.
+func splitLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+// matchingLines returns the indexes of the lines matching the heading regex.
+func matchingLines(lines []string, chapterRegex *regexp.Regexp) []int {
+	var titles []int
+	for i, line := range lines {
+		if chapterRegex.MatchString(line) {
+			titles = append(titles, i)
+		}
+	}
+	return titles
+}
+
+// splitAtTitles builds chapters from the given title line indexes (in ascending order).
+// Text before the first title is dropped.
+func splitAtTitles(lines []string, titles []int) []Chapter {
+	chapters := make([]Chapter, 0, len(titles))
+	for i, titleIdx := range titles {
+		end := len(lines)
+		if i+1 < len(titles) {
+			end = titles[i+1]
+		}
+		chapters = append(chapters, Chapter{
+			Title:   strings.TrimSpace(lines[titleIdx]),
+			Content: strings.TrimSpace(strings.Join(lines[titleIdx+1:end], "\n")),
+		})
+	}
+	return chapters
+}
@@ -0,0 +1,111 @@
+package novel
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// book lays out a text with the given lines, each heading followed by a few
+// paragraphs. Lines starting with "|" are body text, kept as they are without the "|".
+func book(lines ...string) string {
+	var b strings.Builder
+	for _, line := range lines {
+		if body, ok := strings.CutPrefix(line, "|"); ok {
+			fmt.Fprintf(&b, "%s\n\n", body)
+			continue
+		}
+		fmt.Fprintf(&b, "%s\n\n", line)
+		for i := range 3 {
+			fmt.Fprintf(&b, "Paragraph %d under %s, long enough to be ordinary body text.\n\n", i+1, line)
+		}
+	}
+	return b.String()
+}
+
+func TestDetectHeadings(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string // Format name, "" for no format
+	}{
+		{
+			name: "chinese chapters in volumes",
+			text: book("第一卷 风起", "第一章 出发", "第二章 山路", "第二卷 云涌", "第三章 入城", "第四章 夜谈"),
+			want: "zh-chapter+zh-volume",
+		},
+		{
+			name: "chinese chapters with a prologue and an epilogue",
+			text: book("楔子", "第一章 出发", "第二章 山路", "第三章 入城", "尾声"),
+			want: "zh-chapter+zh-extra",
+		},
+		{
+			name: "a stray volume-like line in the text",
+			text: book("第一章 出发", "|第一部分：他们都到了。", "第二章 山路", "第三章 入城"),
+			want: "zh-chapter",
+		},
+		{
+			name: "a single prologue",
+			text: book("Prologue", "Chapter 1", "Chapter 2", "Chapter 3"),
+			want: "en-chapter",
+		},
+		{
+			name: "english chapters in parts",
+			text: book("Part One", "Chapter 1", "Chapter 2", "Part Two", "Chapter 3", "Chapter 4"),
+			want: "en-chapter+en-volume",
+		},
+		{
+			name: "headings clustered in a table of contents count once",
+			text: "Chapter 1\nChapter 2\nChapter 3\nChapter 4\n\n" + book("Prologue", "Epilogue"),
+			want: "",
+		},
+		{
+			name: "markdown",
+			text: book("# One", "# Two", "# Three"),
+			want: "markdown",
+		},
+		{
+			name: "no headings",
+			text: book("|Just a paragraph.", "|And another one."),
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := detectHeadings(splitLines(tt.text))
+			got := ""
+			if err == nil {
+				got = format.Name
+			}
+			if got != tt.want {
+				t.Errorf("detectHeadings() = %q, want %q (err %v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestParseMixedHeadings(t *testing.T) {
+	text := book("第一卷 风起", "第一章 出发", "第二章 山路", "第二卷 云涌", "第三章 入城")
+	format, err := detectHeadings(splitLines(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := titlesOf(splitAtTitles(splitLines(text), matchingLines(splitLines(text), format.Regex)))
+	want := []string{"第一卷 风起", "第一章 出发", "第二章 山路", "第二卷 云涌", "第三章 入城"}
+	if strings.Join(titles, "|") != strings.Join(want, "|") {
+		t.Errorf("chapters = %q, want %q", titles, want)
+	}
+	for i, kind := range []HeadingKind{KindVolume, KindChapter, KindChapter, KindVolume, KindChapter} {
+		if got := TitleKind(titles[i]); got != kind {
+			t.Errorf("TitleKind(%q) = %s, want %s", titles[i], got, kind)
+		}
+	}
+}
+
+func titlesOf(chapters []Chapter) []string {
+	titles := make([]string, len(chapters))
+	for i, ch := range chapters {
+		titles[i] = ch.Title
+	}
+	return titles
+}
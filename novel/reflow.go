@@ -0,0 +1,154 @@
+package novel
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Reflow modes, stored per novel in NovelInfo.Reflow.
+const (
+	ReflowAuto = "auto" // Reflow when the text looks hard-wrapped (the default)
+	ReflowOn   = "on"   // Always join wrapped lines
+	ReflowOff  = "off"  // Keep lines as they are
+)
+
+var (
+	gutenbergStart = regexp.MustCompile(`(?i)^\s*\*{3}\s*START OF (?:THE|THIS) PROJECT GUTENBERG.*$`)
+	gutenbergEnd   = regexp.MustCompile(`(?i)^\s*(?:\*{3}\s*END OF (?:THE|THIS) PROJECT GUTENBERG|End of (?:the )?Project Gutenberg).*$`)
+)
+
+// StripGutenberg removes the Project Gutenberg header and license blocks: everything
+// up to the "*** START OF ... ***" line and from the "*** END OF ... ***" line on.
+// Text without the markers is returned unchanged.
+func StripGutenberg(text string) string {
+	lines := strings.Split(text, "\n")
+	start, end := 0, len(lines)
+	for i, line := range lines {
+		if gutenbergStart.MatchString(line) {
+			start = i + 1
+			break
+		}
+	}
+	for i := start; i < len(lines); i++ {
+		if gutenbergEnd.MatchString(lines[i]) {
+			end = i
+			break
+		}
+	}
+	if start == 0 && end == len(lines) {
+		return text
+	}
+	return strings.Join(lines[start:end], "\n")
+}
+
+const (
+	minReflowLines    = 20  // Too few lines to judge the layout
+	minWrapWidth      = 30  // Narrowest line width considered a hard wrap
+	maxWrapWidth      = 120 // Widest line width considered a hard wrap
+	minLineFill       = 0.6 // A filled line is at least this fraction of the wrap width
+	minFilledRatio    = 0.6 // Share of lines filled close to the wrap width
+	minContinuedRatio = 0.5 // Share of lines directly followed by another text line
+)
+
+// NeedsReflow reports whether text looks hard-wrapped: most lines are filled to a
+// similar width of 30-120 characters and run on into the next line, instead of each
+// line being a whole paragraph.
+func NeedsReflow(text string) bool {
+	lines := strings.Split(text, "\n")
+	var lengths []int
+	continued := 0
+	for i, line := range lines {
+		n := utf8.RuneCountInString(strings.TrimSpace(line))
+		if n == 0 {
+			continue
+		}
+		lengths = append(lengths, n)
+		if i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+			continued++
+		}
+	}
+	if len(lengths) < minReflowLines {
+		return false
+	}
+
+	sorted := append([]int(nil), lengths...)
+	sort.Ints(sorted)
+	width := sorted[len(sorted)*9/10] // 90th percentile is the wrap width
+	if width < minWrapWidth || width > maxWrapWidth {
+		return false
+	}
+	filled := 0
+	for _, n := range lengths {
+		if float64(n) >= float64(width)*minLineFill && n <= width+width/10 {
+			filled++
+		}
+	}
+	total := float64(len(lengths))
+	return float64(filled)/total >= minFilledRatio && float64(continued)/total >= minContinuedRatio
+}
+
+// Reflow joins hard-wrapped lines into paragraphs. Blank lines stay paragraph breaks.
+// Lines are joined with a space, except around CJK text which has no word spacing.
+func Reflow(text string) string {
+	var paragraphs []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			paragraphs = append(paragraphs, current.String())
+			current.Reset()
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		t := strings.TrimSpace(line)
+		if t == "" {
+			flush()
+			continue
+		}
+		if current.Len() > 0 {
+			prev, _ := utf8.DecodeLastRuneInString(current.String())
+			next, _ := utf8.DecodeRuneInString(t)
+			if !isCJK(prev) && !isCJK(next) {
+				current.WriteByte(' ')
+			}
+		}
+		current.WriteString(t)
+	}
+	flush()
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// ReflowChapters applies the reflow mode to every chapter's content. In auto mode the
+// decision is made once for the whole book. It reports whether reflow was applied.
+func ReflowChapters(chapters []Chapter, mode string) bool {
+	switch mode {
+	case ReflowOff:
+		return false
+	case ReflowOn:
+	default:
+		var sample strings.Builder
+		for _, ch := range chapters {
+			sample.WriteString(ch.Content)
+			sample.WriteString("\n\n")
+			if sample.Len() > detectBufferSize {
+				break
+			}
+		}
+		if !NeedsReflow(sample.String()) {
+			return false
+		}
+	}
+	for i := range chapters {
+		chapters[i].Content = Reflow(chapters[i].Content)
+	}
+	return true
+}
+
+// isCJK reports whether r belongs to a script written without spaces between words.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) ||
+		(r >= 0x3000 && r <= 0x303F) || // CJK punctuation
+		(r >= 0xFF00 && r <= 0xFFEF) // Full-width forms
+}
@@ -0,0 +1,143 @@
+package novel
+
+import (
+	"strings"
+	"testing"
+)
+
+const gutenbergHeader = `The Project Gutenberg eBook of Moby-Dick
+
+This eBook is for the use of anyone anywhere in the United States.
+
+*** START OF THE PROJECT GUTENBERG EBOOK MOBY-DICK ***
+`
+
+const gutenbergFooter = `
+*** END OF THE PROJECT GUTENBERG EBOOK MOBY-DICK ***
+
+Updated editions will replace the previous one.
+`
+
+func TestStripGutenberg(t *testing.T) {
+	body := "CHAPTER 1. Loomings.\n\nCall me Ishmael."
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"header and footer", gutenbergHeader + body + gutenbergFooter, body},
+		{"header only", gutenbergHeader + body, body},
+		{"older markers", "Header\n*** START OF THIS PROJECT GUTENBERG EBOOK X ***\n" + body + "\nEnd of the Project Gutenberg EBook of X\nLicense", body},
+		{"no markers", body, body},
+		{"a quoted marker in the text", "He said: \"Project Gutenberg\".\n" + body, "He said: \"Project Gutenberg\".\n" + body},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StripGutenberg(tt.text); got != tt.want {
+				t.Errorf("StripGutenberg() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// hardWrap wraps each paragraph at width runes, as plain text editions do.
+func hardWrap(paragraphs []string, width int) string {
+	var out []string
+	for _, p := range paragraphs {
+		var lines []string
+		line := ""
+		for _, word := range strings.Fields(p) {
+			if line != "" && len(line)+1+len(word) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		out = append(out, strings.Join(append(lines, line), "\n"))
+	}
+	return strings.Join(out, "\n\n")
+}
+
+var englishParagraphs = []string{
+	strings.Repeat("It was the best of times, it was the worst of times, it was the age of wisdom. ", 8),
+	strings.Repeat("There were a king with a large jaw and a queen with a plain face on the throne. ", 8),
+	strings.Repeat("In both countries it was clearer than crystal that things in general were settled. ", 8),
+}
+
+func TestNeedsReflow(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{"hard-wrapped at 70", hardWrap(englishParagraphs, 70), true},
+		{"one paragraph per line", strings.Join(englishParagraphs, "\n"), false},
+		{"paragraphs separated by blank lines", strings.Join(englishParagraphs, "\n\n"), false},
+		{"too short to judge", hardWrap(englishParagraphs[:1], 200), false},
+		{"short lines of verse", strings.Repeat("The rose is red,\nthe violet blue,\n\n", 20), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NeedsReflow(tt.text); got != tt.want {
+				t.Errorf("NeedsReflow() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReflow(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "english lines joined with spaces",
+			text: "It was the best of times,\nit was the worst of times.\n\nThe next paragraph\n  starts here.",
+			want: "It was the best of times, it was the worst of times.\n\nThe next paragraph starts here.",
+		},
+		{
+			name: "chinese lines joined without spaces",
+			text: "天下大势，分久必合，\n合久必分。\n\n周末七国分争，\n并入于秦。",
+			want: "天下大势，分久必合，合久必分。\n\n周末七国分争，并入于秦。",
+		},
+		{
+			name: "mixed scripts",
+			text: "他说：\nOK，\n我们走。",
+			want: "他说：OK，我们走。",
+		},
+		{
+			name: "several blank lines are one break",
+			text: "One.\n\n\n\nTwo.",
+			want: "One.\n\nTwo.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Reflow(tt.text); got != tt.want {
+				t.Errorf("Reflow() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReflowWrappedChapters(t *testing.T) {
+	chapters := []Chapter{
+		{Title: "One", Content: hardWrap(englishParagraphs, 70)},
+		{Title: "Two", Content: hardWrap(englishParagraphs[1:], 70)},
+	}
+	if !ReflowChapters(chapters, ReflowAuto) {
+		t.Fatal("hard-wrapped chapters were not reflowed")
+	}
+	if got := strings.Split(chapters[0].Content, "\n\n"); len(got) != len(englishParagraphs) || got[0] != strings.TrimSpace(englishParagraphs[0]) {
+		t.Errorf("reflowed paragraphs = %q, want the original paragraphs", got)
+	}
+
+	kept := []Chapter{{Title: "One", Content: hardWrap(englishParagraphs, 70)}}
+	if ReflowChapters(kept, ReflowOff) || kept[0].Content != hardWrap(englishParagraphs, 70) {
+		t.Error("chapters were reflowed with reflow off")
+	}
+}
@@ -0,0 +1,231 @@
+package novel
+
+import (
+	"bufio"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// File is one text file making up a novel.
+type File struct {
+	Name    string // Path relative to the novel's directory, or the base name for single files
+	Content string
+}
+
+// textExtensions lists the file extensions read from novel directories.
+var textExtensions = map[string]bool{".txt": true, ".text": true, ".md": true, ".markdown": true}
+
+// Source locates a novel's text: a single file, a directory of chapter files, or an
+// archive (zip, tar, tar.gz, gz) read on demand.
+type Source struct {
+	Path   string // File, directory or archive path
+	Member string // Archive member to read; empty reads every text member
+}
+
+// Files returns the text files making up the novel: the file itself, the selected
+// archive member, or for a directory (or an archive without a member) every text
+// file in natural order (2.txt before 10.txt).
+func (s Source) Files() ([]File, error) {
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() && IsArchive(s.Path) {
+		return readArchive(s.Path, s.Member)
+	}
+	if !info.IsDir() {
+		data, err := os.ReadFile(s.Path)
+		if err != nil {
+			return nil, err
+		}
+		return []File{{Name: filepath.Base(s.Path), Content: string(data)}}, nil
+	}
+
+	names, err := textFilesIn(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]File, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(s.Path, name))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: name, Content: string(data)})
+	}
+	return files, nil
+}
+
+// Title returns a default display title for the novel: the name of the file,
+// directory or archive without text and archive extensions.
+func (s Source) Title() string {
+	name := filepath.Base(s.Path)
+	for {
+		ext := filepath.Ext(name)
+		if ext == "" || ext == name || (!textExtensions[strings.ToLower(ext)] && archiveKind(ext) == "") {
+			return name
+		}
+		name = strings.TrimSuffix(name, ext)
+	}
+}
+
+// textFilesIn lists the text files below dir, relative to it, in natural order.
+// Hidden files and directories are skipped.
+func textFilesIn(dir string) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !textExtensions[strings.ToLower(filepath.Ext(p))] {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortNatural(names)
+	return names, nil
+}
+
+// Stat returns the size and latest modification time of the novel. For a directory
+// these are the total size and newest time of its text files, so adding, removing or
+// editing a chapter file is noticed. Archives report the archive file itself.
+func (s Source) Stat() (size int64, modTime time.Time, err error) {
+	path := s.Path
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	if !info.IsDir() {
+		return info.Size(), info.ModTime(), nil
+	}
+	modTime = info.ModTime()
+	names, err := textFilesIn(path)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	for _, name := range names {
+		fi, err := os.Stat(filepath.Join(path, name))
+		if err != nil {
+			return 0, time.Time{}, err
+		}
+		size += fi.Size()
+		if fi.ModTime().After(modTime) {
+			modTime = fi.ModTime()
+		}
+	}
+	return size, modTime, nil
+}
+
+// readSample returns up to detectBufferSize bytes from the start of a single file.
+func readSample(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	buffer := make([]byte, detectBufferSize) // Read up to 1MB
+	n, err := io.ReadFull(reader, buffer)
+	// io.ReadFull returns io.ErrUnexpectedEOF if less than buffer size is read, which is expected for smaller files.
+	// It returns io.EOF only if 0 bytes were read.
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return string(buffer[:n]), nil
+}
+
+// joinFiles concatenates file contents on separate lines, stopping once limit bytes
+// are reached (limit <= 0 means no limit).
+func joinFiles(files []File, limit int) string {
+	var b strings.Builder
+	for _, f := range files {
+		b.WriteString(f.Content)
+		b.WriteString("\n")
+		if limit > 0 && b.Len() >= limit {
+			break
+		}
+	}
+	return b.String()
+}
+
+// fileChapter turns a whole file into one chapter. The title is the file's first line
+// if it is a recognisable heading, otherwise the file name without its extension.
+func fileChapter(f File) Chapter {
+	lines := splitLines(StripGutenberg(f.Content))
+	for i, line := range lines {
+		t := strings.TrimSpace(line)
+		if t == "" {
+			continue
+		}
+		for _, rule := range HeadingRules {
+			if rule.Pattern.MatchString(t) {
+				return Chapter{Title: t, Content: strings.TrimSpace(strings.Join(lines[i+1:], "\n"))}
+			}
+		}
+		break
+	}
+	name := filepath.Base(f.Name)
+	return Chapter{
+		Title:   strings.TrimSuffix(name, filepath.Ext(name)),
+		Content: strings.TrimSpace(strings.Join(lines, "\n")),
+	}
+}
+
+// sortNatural sorts names so that embedded numbers compare by value.
+func sortNatural(names []string) {
+	sort.SliceStable(names, func(i, j int) bool { return naturalLess(names[i], names[j]) })
+}
+
+// naturalLess compares strings treating runs of digits as numbers: "2" < "10".
+func naturalLess(a, b string) bool {
+	ar, br := []rune(a), []rune(b)
+	i, j := 0, 0
+	for i < len(ar) && j < len(br) {
+		if unicode.IsDigit(ar[i]) && unicode.IsDigit(br[j]) {
+			si, sj := i, j
+			for i < len(ar) && unicode.IsDigit(ar[i]) {
+				i++
+			}
+			for j < len(br) && unicode.IsDigit(br[j]) {
+				j++
+			}
+			na := strings.TrimLeft(string(ar[si:i]), "0")
+			nb := strings.TrimLeft(string(br[sj:j]), "0")
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			if na != nb {
+				return na < nb
+			}
+			continue
+		}
+		ca, cb := unicode.ToLower(ar[i]), unicode.ToLower(br[j])
+		if ca != cb {
+			return ca < cb
+		}
+		i++
+		j++
+	}
+	return len(ar)-i < len(br)-j
+}
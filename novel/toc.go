@@ -0,0 +1,105 @@
+package novel
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	minTOCEntries  = 3  // Fewest dense title lines treated as a table of contents
+	maxTOCGapRunes = 40 // Most body text allowed between two table of contents entries
+)
+
+// splitTOC detects a table of contents at the top of the text: a dense run of title
+// lines with little or no body text between them. It returns the remaining title
+// indexes and the titles listed in the table of contents, which never become chapters.
+//
+// When a later title repeats the first entry, the real chapter list starts there.
+// Otherwise the run ends at the first title followed by real body text.
+func splitTOC(lines []string, titles []int) (remaining []int, toc []string) {
+	run := 0
+	for run+1 < len(titles) && bodyRunes(lines, titles[run]+1, titles[run+1]) <= maxTOCGapRunes {
+		run++
+	}
+	// A dense run must be followed by real chapters, or the whole text is just short.
+	if run < minTOCEntries || run == len(titles)-1 {
+		return titles, nil
+	}
+
+	first := normalizeTitle(lines[titles[0]])
+	end := run
+	for i := 1; i <= run; i++ {
+		if sameTitle(first, normalizeTitle(lines[titles[i]])) {
+			end = i
+			break
+		}
+	}
+
+	for _, idx := range titles[:end] {
+		toc = append(toc, strings.TrimSpace(lines[idx]))
+	}
+	fmt.Printf("Skipped table of contents with %d entries.\n", len(toc))
+	return titles[end:], toc
+}
+
+// validateTOC warns about table of contents entries with no matching chapter.
+func validateTOC(toc []string, chapters []Chapter) {
+	if len(toc) == 0 {
+		return
+	}
+	missing := 0
+	j := 0
+	for _, entry := range toc {
+		want := normalizeTitle(entry)
+		found := false
+		for k := j; k < len(chapters); k++ {
+			if sameTitle(want, normalizeTitle(chapters[k].Title)) {
+				j = k + 1
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing++
+		}
+	}
+	if missing > 0 {
+		fmt.Printf("Warning: %d of %d table of contents entries have no matching chapter.\n", missing, len(toc))
+	}
+}
+
+// tocLeader matches leader dots and a page number at the end of a contents entry.
+var tocLeader = regexp.MustCompile(`[.·…]{2,}[\s\d]*$`)
+
+// normalizeTitle drops whitespace and trailing leader dots with page numbers,
+// so "第一章  开始 ...... 12" compares equal to "第一章 开始".
+func normalizeTitle(title string) string {
+	title = tocLeader.ReplaceAllString(strings.TrimSpace(title), "")
+	var b strings.Builder
+	for _, r := range title {
+		if !unicode.IsSpace(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// sameTitle reports whether two normalized titles refer to the same chapter.
+// Table of contents entries are sometimes shortened, so a prefix match counts,
+// as long as it doesn't split a number ("Chapter1" is not "Chapter10").
+func sameTitle(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if !strings.HasPrefix(b, a) {
+		return false
+	}
+	next, _ := utf8.DecodeRuneInString(b[len(a):])
+	return !unicode.IsDigit(next)
+}
@@ -0,0 +1,87 @@
+package novel
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// zhChapter matches the chapter headings of the test texts.
+var zhChapter = HeadingRules[0].Pattern
+
+func TestSplitTOC(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    []string // Titles left to split at
+		wantTOC []string
+	}{
+		{
+			name: "contents repeated by the chapters",
+			text: "目录\n第一章 出发\n第二章 山路\n第三章 入城\n第四章 夜谈\n\n" +
+				book("第一章 出发", "第二章 山路", "第三章 入城", "第四章 夜谈"),
+			want:    []string{"第一章 出发", "第二章 山路", "第三章 入城", "第四章 夜谈"},
+			wantTOC: []string{"第一章 出发", "第二章 山路", "第三章 入城", "第四章 夜谈"},
+		},
+		{
+			name: "entries with leader dots and page numbers",
+			text: "第一章 出发 ........ 1\n第二章 山路 ........ 9\n第三章 入城 ....... 17\n第四章 夜谈 ....... 25\n\n" +
+				book("第一章 出发", "第二章 山路", "第三章 入城", "第四章 夜谈"),
+			want:    []string{"第一章 出发", "第二章 山路", "第三章 入城", "第四章 夜谈"},
+			wantTOC: []string{"第一章 出发 ........ 1", "第二章 山路 ........ 9", "第三章 入城 ....... 17", "第四章 夜谈 ....... 25"},
+		},
+		{
+			name:    "shortened entries",
+			text:    "第一章\n第二章\n第三章\n第四章\n\n" + book("第一章 出发", "第二章 山路", "第三章 入城", "第四章 夜谈"),
+			want:    []string{"第一章 出发", "第二章 山路", "第三章 入城", "第四章 夜谈"},
+			wantTOC: []string{"第一章", "第二章", "第三章", "第四章"},
+		},
+		{
+			name: "no contents",
+			text: book("第一章 出发", "第二章 山路", "第三章 入城", "第四章 夜谈"),
+			want: []string{"第一章 出发", "第二章 山路", "第三章 入城", "第四章 夜谈"},
+		},
+		{
+			name: "a short text of headings only is not contents",
+			text: "第一章 出发\n第二章 山路\n第三章 入城\n第四章 夜谈\n",
+			want: []string{"第一章 出发", "第二章 山路", "第三章 入城", "第四章 夜谈"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := splitLines(tt.text)
+			remaining, toc := splitTOC(lines, matchingLines(lines, zhChapter))
+			var got []string
+			for _, idx := range remaining {
+				got = append(got, lines[idx])
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("titles = %q, want %q", got, tt.want)
+			}
+			if !slices.Equal(toc, tt.wantTOC) {
+				t.Errorf("contents = %q, want %q", toc, tt.wantTOC)
+			}
+		})
+	}
+}
+
+func TestParseNovelSkipsTOC(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "novel.txt")
+	text := "目录\n第一章 出发\n第二章 山路\n第三章 入城\n\n" + book("第一章 出发", "第二章 山路", "第三章 入城")
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	src := Source{Path: path}
+	format, err := DetectFormat(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chapters, err := ParseNovel(src, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := titlesOf(chapters), []string{"第一章 出发", "第二章 山路", "第三章 入城"}; !slices.Equal(got, want) {
+		t.Errorf("chapters = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/xqbumu/go-novel-reader/playback"
+	"github.com/xqbumu/go-novel-reader/screen"
+	"github.com/xqbumu/go-novel-reader/tts"
+)
+
+// playSaveInterval is how many spoken segments pass between progress saves in play mode.
+const playSaveInterval = 20
+
+// playKeysHelp lists the keys of play mode.
+const playKeysHelp = "Keys: space pause/resume, ←/→ or h/l segment, ↑/↓ or k/j chapter, r replay, +/- speed, b bookmark, q quit"
+
+// restoreTerminal puts the terminal back to normal while play mode has it in raw mode.
+var restoreTerminal func()
+
+// playKeys maps the keys of play mode to player commands.
+var playKeys = map[string]playback.Action{
+	" ":             playback.TogglePause,
+	"p":             playback.TogglePause,
+	"l":             playback.NextSegment,
+	screen.KeyRight: playback.NextSegment,
+	"h":             playback.PrevSegment,
+	screen.KeyLeft:  playback.PrevSegment,
+	"j":             playback.NextChapter,
+	"]":             playback.NextChapter,
+	screen.KeyDown:  playback.NextChapter,
+	"k":             playback.PrevChapter,
+	"[":             playback.PrevChapter,
+	screen.KeyUp:    playback.PrevChapter,
+	"r":             playback.Replay,
+	"+":             playback.Faster,
+	"=":             playback.Faster,
+	"-":             playback.Slower,
+	"_":             playback.Slower,
+	"b":             playback.Bookmark,
+	"q":             playback.Quit,
+	screen.KeyCtrlC: playback.Quit, // Raw mode delivers Ctrl+C as a key
+	screen.KeyCtrlD: playback.Quit,
+}
+
+// handlePlay reads the active novel aloud under keyboard control, until the end or one
+// of the limits its flags set.
+func handlePlay(args []string) {
+	fs := flag.NewFlagSet("play", flag.ExitOnError)
+	limitsFor := limitFlags(fs)
+	if extra := parseCommandFlags(fs, args); len(extra) > 0 {
+		log.Fatalf("Error: play starts where reading left off; use 'seek %s' to move first.", strings.Join(extra, " "))
+	}
+
+	if activeNovel == nil {
+		fmt.Println("No active novel selected. Use 'switch <novel>' first.")
+		return
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		log.Fatal("Error: 'play' needs an interactive terminal. Use 'read' instead.")
+	}
+	if !acquireReaderLock() {
+		return
+	}
+	loadActiveNovelChapters()
+	if len(activeNovel.Chapters) == 0 {
+		fmt.Printf("Chapters not loaded for '%s'.\n", activeNovel.FilePath)
+		return
+	}
+
+	text := activeText()
+	start := text.saved(currentProgress())
+	limits, err := limitsFor(text, start)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	player := playback.New(text.segments, start)
+	player.Speaker = &tts.Speaker{Voice: cfg.Voice, Rate: cfg.Rate}
+	player.Filter = text.filter
+	player.AutoNext = cfg.AutoReadNext || limits != (playback.Limits{})
+	player.Limits = limits
+
+	fmt.Printf("Playing '%s'. %s\n", activeNovel.Title, playKeysHelp)
+	describeLimits(text, start, limits)
+	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		log.Fatalf("Error switching the terminal to raw mode: %v", err)
+	}
+	ps := &playScreen{player: player, out: os.Stdout, chapter: -1, rate: speakingRate()}
+	restoreTerminal = func() {
+		ps.clearStatus()
+		term.Restore(int(os.Stdin.Fd()), oldState)
+	}
+	player.Sinks = []playback.Sink{
+		ps,
+		&progressSaver{text: text, interval: playSaveInterval, save: ps.saveQuietly},
+		playback.SinkFunc(logSession),
+	}
+
+	err = player.Run(readPlayKeys(os.Stdin))
+	restoreTerminal()
+	restoreTerminal = nil
+
+	if err != nil {
+		log.Printf("Error during TTS: %v", err)
+	}
+	if rate := player.Speaker.Rate; rate != cfg.Rate {
+		cfg.Rate = rate
+		configDirty = true
+	}
+}
+
+// readPlayKeys sends the commands for keys typed on r until it ends.
+func readPlayKeys(r io.Reader) <-chan playback.Command {
+	commands := make(chan playback.Command)
+	go func() {
+		defer close(commands)
+		for key := range screen.ReadKeys(r) {
+			if action, ok := playKeys[key]; ok {
+				commands <- playback.Command{Action: action}
+			}
+		}
+	}()
+	return commands
+}
+
+// playScreen shows the text being spoken above a status line, as a sink of the player.
+// The terminal is in raw mode, so lines end in "\r\n".
+type playScreen struct {
+	player  *playback.Player
+	out     io.Writer
+	chapter int     // Chapter whose heading was shown last
+	rate    float64 // Characters per minute from the reading history, 0 if unknown
+}
+
+func (s *playScreen) Handle(e playback.Event) {
+	switch e.Kind {
+	case playback.SegmentCued:
+		s.showSegment(e.Pos, e.Text)
+	case playback.Updated:
+		s.drawStatus()
+	case playback.Marked:
+		if b, err := saveBookmark("", "", e.Pos.Chapter, e.Pos.Segment); err != nil {
+			s.printf("Error: %v", err)
+		} else {
+			s.printf("Bookmarked '%s'.", b.Name)
+		}
+	case playback.Ended:
+		switch e.Reason {
+		case playback.EndOfNovel:
+			s.printf("Reached the end of the novel.")
+		case playback.LimitReached:
+			progInfo := currentProgress()
+			s.printf("Reading limit reached. Next time reading continues at Chapter %d, Segment %d.",
+				progInfo.LastReadChapterIndex+1, progInfo.LastReadSegmentIndex)
+		}
+	}
+}
+
+// saveQuietly saves changed progress; saveProgress would print over the status line.
+func (s *playScreen) saveQuietly() {
+	if !progressDirty {
+		return
+	}
+	if err := store.SaveProgress(progressData); err != nil {
+		s.printf("Error saving progress: %v", err)
+		return
+	}
+	progressDirty = false
+}
+
+// showSegment prints a segment, preceded by its chapter heading when it changes.
+func (s *playScreen) showSegment(pos playback.Position, text string) {
+	if pos.Chapter != s.chapter {
+		s.chapter = pos.Chapter
+		s.printf("--- Chapter %d: %s ---", pos.Chapter+1, activeNovel.Chapters[pos.Chapter].Title)
+	}
+	s.printf("[Segment %d/%d]\n%s", pos.Segment+1, s.player.Segments(pos.Chapter), text)
+}
+
+// printf prints a message above the status line.
+func (s *playScreen) printf(format string, args ...any) {
+	s.clearStatus()
+	msg := strings.ReplaceAll(fmt.Sprintf(format, args...), "\n", "\r\n")
+	fmt.Fprint(s.out, msg+"\r\n")
+	s.drawStatus()
+}
+
+func (s *playScreen) clearStatus() {
+	fmt.Fprint(s.out, "\r\x1b[K")
+}
+
+// drawStatus redraws the status line.
+func (s *playScreen) drawStatus() {
+	read, total := s.player.Progress()
+	status := playStatus(s.player.Position(), read, total, s.player.State(), s.player.Speaker.Rate, sessionRate(s.rate))
+	if width, _, err := term.GetSize(int(os.Stdin.Fd())); err == nil && width > 1 {
+		status = screen.Truncate(status, width-1) // Keep it on one line so it can be redrawn
+	}
+	fmt.Fprint(s.out, "\r\x1b[K"+status)
+}
+
+// playStatus describes the playback of the active novel in one line: position,
+// percentage read, time left at charsPerMinute, state and speaking rate.
+func playStatus(pos playback.Position, read, total int, state playback.State, rate int, charsPerMinute float64) string {
+	percent := 0.0
+	if total > 0 {
+		percent = float64(read) * 100 / float64(total)
+	}
+	eta := "--"
+	if charsPerMinute > 0 {
+		eta = formatDuration(time.Duration(float64(total-read) / charsPerMinute * float64(time.Minute)))
+	}
+	segments := len(segmentSeparator.Split(activeNovel.Chapters[pos.Chapter].Content, -1))
+	return fmt.Sprintf("Ch %d/%d | Seg %d/%d | %.1f%% | ETA %s | %s | %s",
+		pos.Chapter+1, len(activeNovel.Chapters), pos.Segment+1, segments, percent, eta, state, rateName(rate))
+}
+
+// sessionRate returns the speaking rate of the running session once something was
+// spoken, otherwise fallback.
+func sessionRate(fallback float64) float64 {
+	if session != nil && session.Segments > 0 {
+		if d := session.End.Sub(session.Start); d > 0 {
+			return float64(session.Chars) / d.Minutes()
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,434 @@
+package playback
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xqbumu/go-novel-reader/tts"
+)
+
+// EventKind is what happened in an Event.
+type EventKind int
+
+const (
+	ChapterStarted  EventKind = iota // Reading entered Pos.Chapter, at the start or by moving on
+	SegmentCued                      // Pos became the current segment; it is spoken at once when playing
+	SegmentStarted                   // Text is being spoken
+	SegmentFinished                  // Text was spoken to its end
+	Updated                          // The state or the speaking rate changed
+	Marked                           // A Mark control asked to remember Pos, such as for a bookmark
+	Failed                           // Err stopped reading
+	Ended                            // Reading stopped without an error, see Reason
+)
+
+// EndReason tells why an Engine stopped reading.
+type EndReason int
+
+const (
+	EndOfNovel    EndReason = iota // The last segment was read
+	SingleSegment                  // AutoNext is off and a segment was read
+	Canceled                       // The context was canceled or a Stop control received
+	LimitReached                   // One of the Limits was reached
+)
+
+// State is what an Engine is doing.
+type State int
+
+const (
+	Playing  State = iota // Speaking the current segment
+	Paused                // Holding at the current segment
+	Finished              // The last segment was spoken, or a limit was reached
+	Stopped               // Stopped by a Stop control, cancellation or an error
+)
+
+func (s State) String() string {
+	switch s {
+	case Playing:
+		return "playing"
+	case Paused:
+		return "paused"
+	case Finished:
+		return "finished"
+	default:
+		return "stopped"
+	}
+}
+
+// ControlOp is what a Control asks a running Engine to do.
+type ControlOp int
+
+const (
+	MoveTo      ControlOp = iota // Go to the segment Target picks and speak it when playing
+	PauseResume                  // Pause speech mid-segment, or resume it
+	ChangeRate                   // Change the speaking rate by Delta words per minute
+	Mark                         // Report the current segment in a Marked event
+	Stop                         // Stop reading
+)
+
+// Control steers a running Engine. Target is called on the goroutine running the
+// engine, so it sees the current segment even while reading moves on.
+type Control struct {
+	Op     ControlOp
+	Target func(from Position) (Position, bool) // For MoveTo: the segment to go to from the current one, or false to stay
+	Delta  int                                  // For ChangeRate
+}
+
+// Limits stop an Engine before the end of the novel. Zero values don't limit.
+type Limits struct {
+	EndChapter    int           // Stop at the end of the chapter before this index
+	Duration      time.Duration // Stop after the segment during which this much time passed
+	FinishChapter bool          // Once Duration passed, read to the end of the chapter instead
+	FadeSegments  int           // Lower the volume over about this many segments before a limit stops reading
+}
+
+// Event reports a step of an Engine to its sinks.
+type Event struct {
+	Kind   EventKind
+	Pos    Position
+	Text   string    // Filtered text of the segment, for segment events
+	State  State     // For Updated
+	Err    error     // For Failed
+	Reason EndReason // For Ended
+}
+
+// Sink receives the events of an Engine, on the goroutine running it.
+type Sink interface {
+	Handle(e Event)
+}
+
+// SinkFunc is a function used as a Sink.
+type SinkFunc func(e Event)
+
+func (f SinkFunc) Handle(e Event) { f(e) }
+
+// Engine reads a novel aloud from a position: the segments of a chapter in order and,
+// with AutoNext, the following chapters. Controls, if set, steer it while it reads, as
+// a Player does. Everything else, such as printing, saving progress or logging
+// sessions, is done by its Sinks.
+type Engine struct {
+	Speaker  *tts.Speaker
+	Filter   func(string) string // Applied to each segment before it is spoken
+	AutoNext bool                // Continue with the following chapters; otherwise stop after one segment, or with Controls pause at the next chapter
+	Paused   bool                // With Controls, start Run paused at the first segment instead of speaking it
+	Limits   Limits
+	Sinks    []Sink
+	Controls <-chan Control // Closing it stops Run
+
+	segments [][]string // Unfiltered segments of each chapter
+
+	pos        Position
+	state      State
+	cued       bool // pos was cued, so its chapter has started
+	utt        *tts.Utterance
+	endChapter int // Reading stops at the end of the chapter before this index
+	deadline   time.Time
+	timeUp     bool
+	began      time.Time     // When speaking the current segment began
+	spoken     time.Duration // Time spent speaking finished segments, to estimate how many fit before the deadline
+	finished   int
+}
+
+// NewEngine returns an engine over the segments of each chapter.
+func NewEngine(segments [][]string) *Engine {
+	return &Engine{
+		Speaker:  &tts.Speaker{},
+		Filter:   func(s string) string { return s },
+		segments: segments,
+	}
+}
+
+// Position returns the current segment: the one being spoken, or where reading holds
+// or stopped. Like State, it may be called from sinks and after Run returned.
+func (e *Engine) Position() Position { return e.pos }
+
+// State returns what the engine is doing.
+func (e *Engine) State() State { return e.state }
+
+// Run reads from start until the novel ends, one segment was read with AutoNext off
+// and no Controls, a limit is reached, speaking fails, a Stop control is received or
+// ctx is canceled. Segments without text after filtering are skipped. It returns the
+// error reading failed with, also reported to the sinks, or ctx.Err() if canceled.
+func (e *Engine) Run(ctx context.Context, start Position) error {
+	if !e.valid(start) {
+		err := fmt.Errorf("chapter %d, segment %d is not in the novel", start.Chapter+1, start.Segment)
+		e.state = Stopped
+		e.emit(Event{Kind: Failed, Pos: start, Err: err})
+		return err
+	}
+	defer e.silence()
+	e.pos, e.cued, e.timeUp, e.spoken, e.finished = start, false, false, 0, 0
+	e.state = Playing
+	if e.Paused && e.Controls != nil {
+		e.state = Paused
+	}
+	e.endChapter = len(e.segments)
+	if e.Limits.EndChapter > 0 {
+		e.endChapter = min(e.endChapter, max(e.Limits.EndChapter, start.Chapter+1))
+	}
+	e.deadline = time.Now().Add(e.Limits.Duration)
+
+	first := start
+	if !e.hasText(start) {
+		next, ok := e.next(start, 1)
+		if !ok {
+			return e.end(EndOfNovel)
+		}
+		if next.Chapter >= e.endChapter {
+			return e.end(LimitReached)
+		}
+		first = next
+	}
+	if err := e.moveTo(first); err != nil {
+		return err
+	}
+
+	for e.state == Playing || e.state == Paused {
+		var done <-chan error
+		if e.utt != nil {
+			done = e.utt.Done()
+		}
+		var err error
+		select {
+		case err = <-done:
+			e.utt = nil
+			if err != nil {
+				return e.fail(err)
+			}
+			err = e.finish()
+		case c, ok := <-e.Controls:
+			if !ok {
+				c = Control{Op: Stop}
+			}
+			err = e.control(c)
+		case <-ctx.Done():
+			e.end(Canceled)
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finish moves on from the current segment, which was spoken to its end.
+func (e *Engine) finish() error {
+	e.spoken += time.Since(e.began)
+	e.finished++
+	e.emit(Event{Kind: SegmentFinished, Pos: e.pos, Text: e.text(e.pos)})
+	if !e.AutoNext && e.Controls == nil {
+		return e.end(SingleSegment)
+	}
+	if e.Limits.Duration > 0 && !e.timeUp && !time.Now().Before(e.deadline) {
+		e.timeUp = true
+		if !e.Limits.FinishChapter {
+			return e.end(LimitReached)
+		}
+		e.endChapter = e.pos.Chapter + 1
+	}
+	next, ok := e.next(e.pos, 1)
+	switch {
+	case !ok:
+		return e.end(EndOfNovel)
+	case next.Chapter >= e.endChapter:
+		return e.end(LimitReached)
+	case next.Chapter != e.pos.Chapter && !e.AutoNext:
+		e.setState(Paused)
+	}
+	return e.moveTo(next)
+}
+
+// control applies a control received while reading.
+func (e *Engine) control(c Control) error {
+	switch c.Op {
+	case MoveTo:
+		if to, ok := c.Target(e.pos); ok && e.hasText(to) {
+			return e.moveTo(to)
+		}
+	case PauseResume:
+		switch {
+		case e.state == Playing:
+			if e.utt != nil && e.utt.Pause() != nil {
+				e.silence() // Can't suspend speech; say the segment again on resume
+			}
+			e.setState(Paused)
+		case e.utt != nil:
+			e.setState(Playing)
+			if e.utt.Resume() != nil {
+				return e.moveTo(e.pos)
+			}
+		default:
+			e.setState(Playing)
+			return e.speak()
+		}
+	case ChangeRate:
+		rate := cmp.Or(e.Speaker.Rate, tts.DefaultRate)
+		e.Speaker.Rate = min(max(rate+c.Delta, tts.MinRate), tts.MaxRate)
+		e.emit(Event{Kind: Updated, Pos: e.pos, State: e.state})
+		if e.state == Playing {
+			return e.moveTo(e.pos) // Say the segment again at the new rate
+		}
+	case Mark:
+		e.emit(Event{Kind: Marked, Pos: e.pos, Text: e.text(e.pos)})
+	case Stop:
+		return e.end(Canceled)
+	}
+	return nil
+}
+
+// moveTo stops speaking and cues pos, speaking it when playing.
+func (e *Engine) moveTo(pos Position) error {
+	e.silence()
+	newChapter := !e.cued || pos.Chapter != e.pos.Chapter
+	e.pos, e.cued = pos, true
+	if newChapter {
+		e.emit(Event{Kind: ChapterStarted, Pos: pos})
+	}
+	e.emit(Event{Kind: SegmentCued, Pos: pos, Text: e.text(pos)})
+	if e.state != Playing {
+		return nil
+	}
+	return e.speak()
+}
+
+// speak starts speaking the current segment.
+func (e *Engine) speak() error {
+	var perSegment time.Duration
+	if e.finished > 0 && !e.timeUp {
+		perSegment = e.spoken / time.Duration(e.finished)
+	}
+	e.Speaker.Volume = e.volume(e.pos, perSegment)
+	text := e.text(e.pos)
+	e.emit(Event{Kind: SegmentStarted, Pos: e.pos, Text: text})
+	utt, err := e.Speaker.Start(text)
+	if err != nil {
+		return e.fail(err)
+	}
+	e.utt, e.began = utt, time.Now()
+	return nil
+}
+
+// silence stops the segment being spoken, if any.
+func (e *Engine) silence() {
+	if e.utt != nil {
+		e.utt.Stop()
+		e.utt = nil
+	}
+}
+
+// fail stops reading because of err and returns it.
+func (e *Engine) fail(err error) error {
+	e.silence()
+	e.setState(Stopped)
+	e.emit(Event{Kind: Failed, Pos: e.pos, Text: e.text(e.pos), Err: err})
+	return err
+}
+
+// end stops reading for reason.
+func (e *Engine) end(reason EndReason) error {
+	e.silence()
+	if reason == Canceled {
+		e.setState(Stopped)
+	} else {
+		e.setState(Finished)
+	}
+	e.emit(Event{Kind: Ended, Pos: e.pos, Reason: reason})
+	return nil
+}
+
+func (e *Engine) setState(s State) {
+	if e.state != s {
+		e.state = s
+		e.emit(Event{Kind: Updated, Pos: e.pos, State: s})
+	}
+}
+
+// volume returns the volume to speak the segment at pos with: full, or lower the
+// closer a limit is to stop reading within Limits.FadeSegments segments. perSegment
+// (0 if unknown) estimates the segments left until the deadline.
+func (e *Engine) volume(pos Position, perSegment time.Duration) float64 {
+	n := e.Limits.FadeSegments
+	if n <= 0 {
+		return 1
+	}
+	left := n + 1 // Segments left including this one; more than n means no fading yet
+	if e.endChapter < len(e.segments) {
+		left = e.textSegmentsLeft(pos, e.endChapter, n+1)
+	}
+	if e.Limits.Duration > 0 && !e.Limits.FinishChapter && perSegment > 0 {
+		left = min(left, int(time.Until(e.deadline)/perSegment)+1)
+	}
+	if left > n {
+		return 1
+	}
+	return float64(max(left, 1)) / float64(n+1)
+}
+
+// textSegmentsLeft counts the segments with text from pos to the end of the chapter
+// before endChapter, up to limit.
+func (e *Engine) textSegmentsLeft(pos Position, endChapter, limit int) int {
+	count := 0
+	for c := pos.Chapter; c < endChapter && count < limit; c++ {
+		from := 0
+		if c == pos.Chapter {
+			from = pos.Segment
+		}
+		for _, segment := range e.segments[c][from:] {
+			if strings.TrimSpace(e.Filter(segment)) != "" {
+				count++
+			}
+		}
+	}
+	return min(count, limit)
+}
+
+// emit passes an event to every sink in order.
+func (e *Engine) emit(ev Event) {
+	for _, sink := range e.Sinks {
+		sink.Handle(ev)
+	}
+}
+
+// text returns the filtered text of the segment at pos.
+func (e *Engine) text(pos Position) string {
+	return strings.TrimSpace(e.Filter(e.segments[pos.Chapter][pos.Segment]))
+}
+
+func (e *Engine) valid(pos Position) bool {
+	return pos.Chapter >= 0 && pos.Chapter < len(e.segments) &&
+		pos.Segment >= 0 && pos.Segment < len(e.segments[pos.Chapter])
+}
+
+func (e *Engine) hasText(pos Position) bool {
+	return e.valid(pos) && e.text(pos) != ""
+}
+
+// next returns the nearest segment with text after (dir 1) or before (dir -1) from,
+// crossing chapter boundaries. from may be one past either end of a chapter.
+func (e *Engine) next(from Position, dir int) (Position, bool) {
+	pos := from
+	for {
+		pos.Segment += dir
+		for pos.Chapter >= 0 && pos.Chapter < len(e.segments) &&
+			(pos.Segment < 0 || pos.Segment >= len(e.segments[pos.Chapter])) {
+			pos.Chapter += dir
+			if pos.Chapter < 0 || pos.Chapter >= len(e.segments) {
+				break
+			}
+			if dir > 0 {
+				pos.Segment = 0
+			} else {
+				pos.Segment = len(e.segments[pos.Chapter]) - 1
+			}
+		}
+		if !e.valid(pos) {
+			return from, false
+		}
+		if e.hasText(pos) {
+			return pos, true
+		}
+	}
+}
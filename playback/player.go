@@ -0,0 +1,163 @@
+// Package playback reads a novel aloud segment by segment: an Engine reads and reports
+// to sinks, on its own or steered by a Player under interactive control.
+package playback
+
+import (
+	"context"
+	"unicode/utf8"
+)
+
+// RateStep is how much Faster and Slower change the speaking rate, in words per minute.
+const RateStep = 20
+
+// Action is what a Command asks a running Player to do.
+type Action int
+
+const (
+	TogglePause Action = iota
+	NextSegment
+	PrevSegment
+	NextChapter
+	PrevChapter
+	Replay
+	Faster
+	Slower
+	Bookmark
+	Seek // Move to Command.To, or the next segment with text after it
+	Quit
+)
+
+// Command controls a running Player.
+type Command struct {
+	Action Action
+	To     Position // Target of Seek
+}
+
+// Position is a 0-based chapter and segment index.
+type Position struct {
+	Chapter int
+	Segment int
+}
+
+// Player reads a novel aloud under interactive control. It is the controller of an
+// Engine: commands become the engine's controls, and the engine does the reading and
+// reports to the Sinks.
+type Player struct {
+	*Engine
+
+	chapterChars []int // Characters in each chapter, for progress
+	totalChars   int
+}
+
+// New returns a player over the segments of each chapter, positioned at start.
+func New(segments [][]string, start Position) *Player {
+	p := &Player{Engine: NewEngine(segments), chapterChars: make([]int, len(segments))}
+	for i, chapter := range segments {
+		for _, s := range chapter {
+			p.chapterChars[i] += utf8.RuneCountInString(s)
+		}
+		p.totalChars += p.chapterChars[i]
+	}
+	p.pos, p.state = start, Paused
+	if !p.valid(start) {
+		p.pos = Position{}
+	}
+	return p
+}
+
+// Segments returns the number of segments in a chapter.
+func (p *Player) Segments(chapter int) int { return len(p.segments[chapter]) }
+
+// Progress returns the characters before the current segment and in the whole novel.
+func (p *Player) Progress() (read, total int) {
+	pos := p.Position()
+	for i := 0; i < pos.Chapter; i++ {
+		read += p.chapterChars[i]
+	}
+	for _, s := range p.segments[pos.Chapter][:pos.Segment] {
+		read += utf8.RuneCountInString(s)
+	}
+	return read, p.totalChars
+}
+
+// Run plays from the current position until the novel is finished, a limit is
+// reached, Quit is received or the commands channel is closed. It returns an error if
+// speech fails.
+func (p *Player) Run(commands <-chan Command) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	controls := make(chan Control)
+	p.Controls = controls
+	go func() {
+		for {
+			var cmd Command
+			ok := false
+			select {
+			case cmd, ok = <-commands:
+			case <-ctx.Done():
+				return
+			}
+			if !ok {
+				cmd = Command{Action: Quit}
+			}
+			select {
+			case controls <- p.control(cmd):
+			case <-ctx.Done():
+				return
+			}
+			if cmd.Action == Quit {
+				return
+			}
+		}
+	}()
+	return p.Engine.Run(ctx, p.pos)
+}
+
+// control returns the engine control that carries out a command. Targets are worked
+// out from the segment current when the engine applies them.
+func (p *Player) control(cmd Command) Control {
+	var target func(from Position) (Position, bool)
+	switch cmd.Action {
+	case TogglePause:
+		return Control{Op: PauseResume}
+	case NextSegment:
+		target = func(from Position) (Position, bool) { return p.next(from, 1) }
+	case PrevSegment:
+		target = func(from Position) (Position, bool) { return p.next(from, -1) }
+	case NextChapter:
+		target = func(from Position) (Position, bool) {
+			return p.next(Position{Chapter: from.Chapter + 1, Segment: -1}, 1)
+		}
+	case PrevChapter:
+		target = func(from Position) (Position, bool) {
+			// Like a music player: back to the start of this chapter, then the one before.
+			chapter := from.Chapter
+			if start, ok := p.next(Position{Chapter: chapter, Segment: -1}, 1); ok && start == from {
+				chapter = max(chapter-1, 0)
+			}
+			return p.next(Position{Chapter: chapter, Segment: -1}, 1)
+		}
+	case Replay:
+		target = func(from Position) (Position, bool) { return from, true }
+	case Faster:
+		return Control{Op: ChangeRate, Delta: RateStep}
+	case Slower:
+		return Control{Op: ChangeRate, Delta: -RateStep}
+	case Bookmark:
+		return Control{Op: Mark}
+	case Seek:
+		to := cmd.To
+		target = func(Position) (Position, bool) {
+			if p.hasText(to) {
+				return to, true
+			}
+			if p.valid(to) || to.Segment < 0 {
+				return p.next(to, 1)
+			}
+			return to, false
+		}
+	default:
+		return Control{Op: Stop}
+	}
+	return Control{Op: MoveTo, Target: target}
+}
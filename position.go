@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xqbumu/go-novel-reader/config"
+	"github.com/xqbumu/go-novel-reader/novel"
+	"github.com/xqbumu/go-novel-reader/playback"
+)
+
+// positionHelp lists the position forms 'read' and 'seek' accept.
+const positionHelp = "12 (chapter), 12:34 (chapter:segment), 45%, +3/-1 (chapters), +10s/-10s (segments), part of a chapter title"
+
+var (
+	chapterSegmentSpec = regexp.MustCompile(`^(\d+):(\d+)$`)
+	percentSpec        = regexp.MustCompile(`^(\d+(?:\.\d+)?)%$`)
+	relativeSpec       = regexp.MustCompile(`^([+-]\d+)(s?)$`)
+)
+
+// novelText is a novel's chapters split into segments: the positions of a novel.
+type novelText struct {
+	titles   []string
+	segments [][]string
+	filter   func(string) string
+}
+
+// activeText returns the text of the active novel, whose chapters must be loaded.
+func activeText() *novelText {
+	t := &novelText{segments: chapterSegments(activeNovel.Chapters), filter: filterPipeline(activeNovel).Apply}
+	for _, chapter := range activeNovel.Chapters {
+		t.titles = append(t.titles, chapter.Title)
+	}
+	return t
+}
+
+// chapterSegments splits chapters into the segments that are read one at a time.
+func chapterSegments(chapters []novel.Chapter) [][]string {
+	segments := make([][]string, len(chapters))
+	for i, chapter := range chapters {
+		segments[i] = segmentSeparator.Split(chapter.Content, -1)
+	}
+	return segments
+}
+
+// parse resolves a position spec (see positionHelp); relative moves start at from.
+// Chapter numbers are 1-based and segment indexes 0-based, as 'where' shows them.
+func (t *novelText) parse(spec string, from playback.Position) (playback.Position, error) {
+	spec = strings.TrimSpace(spec)
+	if n, err := strconv.Atoi(spec); err == nil && !strings.HasPrefix(spec, "+") && !strings.HasPrefix(spec, "-") {
+		pos := playback.Position{Chapter: n - 1}
+		return pos, t.check(pos)
+	}
+	if m := chapterSegmentSpec.FindStringSubmatch(spec); m != nil {
+		chapter, _ := strconv.Atoi(m[1])
+		segment, _ := strconv.Atoi(m[2])
+		pos := playback.Position{Chapter: chapter - 1, Segment: segment}
+		return pos, t.check(pos)
+	}
+	if m := percentSpec.FindStringSubmatch(spec); m != nil {
+		percent, _ := strconv.ParseFloat(m[1], 64)
+		if percent > 100 {
+			return from, fmt.Errorf("%s is more than 100%%", spec)
+		}
+		return t.atPercent(percent), nil
+	}
+	if m := relativeSpec.FindStringSubmatch(spec); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if m[2] == "s" {
+			return t.stepSegments(from, n)
+		}
+		return t.stepChapters(from, n)
+	}
+	if spec == "" {
+		return from, errors.New("no position given")
+	}
+	return t.findTitle(spec)
+}
+
+// check reports an error if pos is not a segment of the novel.
+func (t *novelText) check(pos playback.Position) error {
+	if pos.Chapter < 0 || pos.Chapter >= len(t.segments) {
+		return fmt.Errorf("chapter %d does not exist; the novel has chapters 1-%d", pos.Chapter+1, len(t.segments))
+	}
+	if pos.Segment < 0 || pos.Segment >= len(t.segments[pos.Chapter]) {
+		return fmt.Errorf("segment %d does not exist; chapter %d has segments 0-%d",
+			pos.Segment, pos.Chapter+1, len(t.segments[pos.Chapter])-1)
+	}
+	return nil
+}
+
+// saved returns the reading position in progInfo, or the start of the novel with a
+// warning if the position is no longer in the novel.
+func (t *novelText) saved(progInfo *config.ProgressInfo) playback.Position {
+	pos := playback.Position{Chapter: progInfo.LastReadChapterIndex, Segment: progInfo.LastReadSegmentIndex}
+	if err := t.check(pos); err != nil {
+		fmt.Printf("Warning: The last read position (Chapter %d, Segment %d) is invalid: %v. Starting from the beginning.\n",
+			pos.Chapter+1, pos.Segment, err)
+		return playback.Position{}
+	}
+	return pos
+}
+
+// stepChapters moves n chapters from the chapter of from, to the chapter's start.
+func (t *novelText) stepChapters(from playback.Position, n int) (playback.Position, error) {
+	chapter := from.Chapter + n
+	switch {
+	case chapter >= len(t.segments):
+		return from, fmt.Errorf("can't move %+d chapters: already at chapter %d of %d", n, from.Chapter+1, len(t.segments))
+	case chapter < 0:
+		return from, fmt.Errorf("can't move %+d chapters: already at chapter %d", n, from.Chapter+1)
+	}
+	return playback.Position{Chapter: chapter}, nil
+}
+
+// stepSegments moves n segments from from, across chapters. Segments left empty by
+// the text filters are skipped, as they are when reading.
+func (t *novelText) stepSegments(from playback.Position, n int) (playback.Position, error) {
+	dir := 1
+	if n < 0 {
+		dir, n = -1, -n
+	}
+	pos := from
+	for moved := 0; moved < n; {
+		pos.Segment += dir
+		for pos.Chapter >= 0 && pos.Chapter < len(t.segments) &&
+			(pos.Segment < 0 || pos.Segment >= len(t.segments[pos.Chapter])) {
+			pos.Chapter += dir
+			if pos.Chapter < 0 || pos.Chapter >= len(t.segments) {
+				break
+			}
+			pos.Segment = 0
+			if dir < 0 {
+				pos.Segment = len(t.segments[pos.Chapter]) - 1
+			}
+		}
+		if pos.Chapter < 0 {
+			return from, errors.New("already at the first segment of the novel")
+		}
+		if pos.Chapter >= len(t.segments) {
+			return from, errors.New("already at the last segment of the novel")
+		}
+		if t.hasText(pos) {
+			moved++
+		}
+	}
+	return pos, nil
+}
+
+// hasText reports whether a segment has anything to read after filtering.
+func (t *novelText) hasText(pos playback.Position) bool {
+	return strings.TrimSpace(t.filter(t.segments[pos.Chapter][pos.Segment])) != ""
+}
+
+// atPercent returns the segment containing the character percent of the way through
+// the novel. Segments left empty by the text filters are skipped, as they are when
+// reading.
+func (t *novelText) atPercent(percent float64) playback.Position {
+	total := 0
+	for c, chapter := range t.segments {
+		for s, segment := range chapter {
+			if t.hasText(playback.Position{Chapter: c, Segment: s}) {
+				total += utf8.RuneCountInString(segment)
+			}
+		}
+	}
+	target := int(float64(total) * percent / 100)
+	var last playback.Position
+	for c, chapter := range t.segments {
+		for s, segment := range chapter {
+			if !t.hasText(playback.Position{Chapter: c, Segment: s}) {
+				continue
+			}
+			n := utf8.RuneCountInString(segment)
+			last = playback.Position{Chapter: c, Segment: s}
+			if target < n {
+				return last
+			}
+			target -= n
+		}
+	}
+	return last // 100%: the last segment
+}
+
+// findTitle returns the start of the chapter whose title is query or, failing that,
+// the only chapter whose title contains it, ignoring case.
+func (t *novelText) findTitle(query string) (playback.Position, error) {
+	q := strings.ToLower(query)
+	var exact, partial []int
+	for i, title := range t.titles {
+		title = strings.ToLower(title)
+		switch {
+		case title == q:
+			exact = append(exact, i)
+		case strings.Contains(title, q):
+			partial = append(partial, i)
+		}
+	}
+	candidates := exact
+	if len(candidates) == 0 {
+		candidates = partial
+	}
+	switch len(candidates) {
+	case 0:
+		return playback.Position{}, fmt.Errorf("'%s' is not a position or part of a chapter title; use %s", query, positionHelp)
+	case 1:
+		return playback.Position{Chapter: candidates[0]}, nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "'%s' matches %d chapters; use the chapter number:", query, len(candidates))
+	for _, i := range candidates[:min(len(candidates), 10)] {
+		fmt.Fprintf(&b, "\n  %d: %s", i+1, t.titles[i])
+	}
+	if len(candidates) > 10 {
+		fmt.Fprintf(&b, "\n  ... and %d more", len(candidates)-10)
+	}
+	return playback.Position{}, errors.New(b.String())
+}
+
+// resolvePosition returns the active novel's position named by a 'read' or 'seek'
+// argument or bookmark name, or the saved reading position if both are empty.
+func resolvePosition(t *novelText, spec, bookmark string) (playback.Position, error) {
+	saved := t.saved(currentProgress())
+	switch {
+	case spec != "" && bookmark != "":
+		return saved, errors.New("give either a position or --from-bookmark, not both")
+	case bookmark != "":
+		b := mustFindBookmark(bookmark)
+		pos := playback.Position{Chapter: b.Chapter, Segment: b.Segment}
+		if err := t.check(pos); err != nil {
+			return saved, fmt.Errorf("bookmark '%s' no longer points into the novel: %v", b.Name, err)
+		}
+		return pos, nil
+	case spec != "":
+		return t.parse(spec, saved)
+	}
+	return saved, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/xqbumu/go-novel-reader/playback"
+)
+
+// removeTildes is the text filter of the test novels: segments of tildes are empty
+// after filtering.
+func removeTildes(s string) string { return strings.ReplaceAll(s, "~", "") }
+
+// longText is a novel of 20 chapters with 40 segments each. The last two segments of
+// chapter 2 and the first of chapter 3 are empty after filtering.
+func longText() *novelText {
+	t := &novelText{filter: removeTildes}
+	for c := range 20 {
+		t.titles = append(t.titles, fmt.Sprintf("Chapter %d", c+1))
+		var segments []string
+		for s := range 40 {
+			segments = append(segments, fmt.Sprintf("Chapter %d, segment %d.", c+1, s))
+		}
+		t.segments = append(t.segments, segments)
+	}
+	t.segments[1][38], t.segments[1][39], t.segments[2][0] = "~~~", "~~~", "~~~"
+	t.titles[4] = "The Duel"
+	t.titles[5] = "Duel at Dawn"
+	t.titles[6] = "Dawn"
+	t.titles[9] = "The Ending"
+	return t
+}
+
+// shortText is a novel of 2 chapters whose segments with text all have 10 characters;
+// chapter 1, segment 2 and the last segment are empty after filtering.
+func shortText() *novelText {
+	ten := strings.Repeat("a", 10)
+	tildes := strings.Repeat("~", 10)
+	return &novelText{
+		titles:   []string{"One", "Two"},
+		segments: [][]string{{ten, ten, tildes, ten, ten}, {ten, ten, ten, ten, ten, tildes}},
+		filter:   removeTildes,
+	}
+}
+
+func pos(chapter, segment int) playback.Position {
+	return playback.Position{Chapter: chapter, Segment: segment}
+}
+
+func TestParsePosition(t *testing.T) {
+	tests := []struct {
+		text    *novelText
+		spec    string
+		from    playback.Position
+		want    playback.Position
+		wantErr string // Part of the expected error
+	}{
+		// Chapters and chapter:segment, as 'where' shows them.
+		{text: longText(), spec: "12", want: pos(11, 0)},
+		{text: longText(), spec: " 12 ", want: pos(11, 0)},
+		{text: longText(), spec: "12:34", want: pos(11, 34)},
+		{text: longText(), spec: "1:0", want: pos(0, 0)},
+		{text: longText(), spec: "21", wantErr: "chapter 21 does not exist; the novel has chapters 1-20"},
+		{text: longText(), spec: "0", wantErr: "chapter 0 does not exist"},
+		{text: longText(), spec: "12:40", wantErr: "segment 40 does not exist; chapter 12 has segments 0-39"},
+
+		// Percentages of the text; segments empty after filtering are skipped.
+		{text: shortText(), spec: "0%", want: pos(0, 0)},
+		{text: shortText(), spec: "25%", want: pos(0, 3)},
+		{text: shortText(), spec: "45%", want: pos(1, 0)},
+		{text: shortText(), spec: "99.9%", want: pos(1, 4)},
+		{text: shortText(), spec: "100%", want: pos(1, 4)},
+		{text: shortText(), spec: "101%", wantErr: "more than 100%"},
+
+		// Relative chapters, to the start of the chapter.
+		{text: longText(), spec: "+3", from: pos(4, 7), want: pos(7, 0)},
+		{text: longText(), spec: "-1", from: pos(4, 7), want: pos(3, 0)},
+		{text: longText(), spec: "+0", from: pos(4, 7), want: pos(4, 0)},
+		{text: longText(), spec: "+16", from: pos(4, 7), wantErr: "already at chapter 5 of 20"},
+		{text: longText(), spec: "-5", from: pos(4, 7), wantErr: "already at chapter 5"},
+
+		// Relative segments, across chapters and past segments empty after filtering.
+		{text: longText(), spec: "+1s", from: pos(0, 5), want: pos(0, 6)},
+		{text: longText(), spec: "+1s", from: pos(0, 39), want: pos(1, 0)},
+		{text: longText(), spec: "-1s", from: pos(1, 0), want: pos(0, 39)},
+		{text: longText(), spec: "+1s", from: pos(1, 37), want: pos(2, 1)},
+		{text: longText(), spec: "-1s", from: pos(2, 1), want: pos(1, 37)},
+		{text: longText(), spec: "+10s", from: pos(1, 30), want: pos(2, 3)},
+		{text: longText(), spec: "-10s", from: pos(2, 3), want: pos(1, 30)},
+		{text: longText(), spec: "+45s", from: pos(5, 0), want: pos(6, 5)},
+		{text: longText(), spec: "-1s", from: pos(0, 0), wantErr: "already at the first segment of the novel"},
+		{text: longText(), spec: "+1s", from: pos(19, 39), wantErr: "already at the last segment of the novel"},
+		{text: shortText(), spec: "+1s", from: pos(1, 4), wantErr: "already at the last segment of the novel"},
+
+		// Chapter titles: exact matches first, then a single partial match.
+		{text: longText(), spec: "The Duel", want: pos(4, 0)},
+		{text: longText(), spec: "dawn", want: pos(6, 0)},
+		{text: longText(), spec: "ending", want: pos(9, 0)},
+		{text: longText(), spec: "duel", wantErr: "'duel' matches 2 chapters; use the chapter number:\n  5: The Duel\n  6: Duel at Dawn"},
+		{text: longText(), spec: "chapter", wantErr: "matches 16 chapters"},
+		{text: longText(), spec: "prologue", wantErr: "'prologue' is not a position or part of a chapter title"},
+		{text: longText(), spec: "", wantErr: "no position given"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := tt.text.parse(tt.spec, tt.from)
+			switch {
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parse(%q, %v) error = %v, want one containing %q", tt.spec, tt.from, err, tt.wantErr)
+				}
+			case err != nil:
+				t.Errorf("parse(%q, %v) error = %v", tt.spec, tt.from, err)
+			case got != tt.want:
+				t.Errorf("parse(%q, %v) = %v, want %v", tt.spec, tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAtPercentSkipsEmptySegments(t *testing.T) {
+	text := shortText()
+	for percent := 0.0; percent <= 100; percent += 0.5 {
+		if p := text.atPercent(percent); !text.hasText(p) {
+			t.Errorf("atPercent(%v) = %v, a segment without text", percent, p)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xqbumu/go-novel-reader/playback"
+	"github.com/xqbumu/go-novel-reader/tts"
+)
+
+// readSaveInterval is how many finished segments 'read' saves progress after.
+const readSaveInterval = 20
+
+// readFadeSegments is how many segments the volume fades out over before a limit
+// stops 'read'.
+const readFadeSegments = 3
+
+// cancelRead stops 'read' so that it ends like a normal exit; nil when not reading.
+var cancelRead context.CancelFunc
+
+// limitFlags defines the flags that limit reading on fs, as 'read' and 'play' take
+// them. The returned function builds the limits for reading from start once fs was
+// parsed.
+func limitFlags(fs *flag.FlagSet) func(text *novelText, start playback.Position) (playback.Limits, error) {
+	to := fs.String("to", "", "stop at the end of this `chapter`")
+	chapters := fs.Int("chapters", 0, "stop after reading this many chapters")
+	duration := fs.Duration("for", 0, "stop after reading this long, e.g. 30m")
+	sleep := fs.Duration("sleep", 0, "sleep timer: like --for, usually with --finish-chapter")
+	finishChapter := fs.Bool("finish-chapter", false, "when --for or --sleep runs out, finish the chapter")
+	return func(text *novelText, start playback.Position) (playback.Limits, error) {
+		if *duration > 0 && *sleep > 0 {
+			return playback.Limits{}, fmt.Errorf("give either --for or --sleep, not both")
+		}
+		return readLimits(text, start, *to, *chapters, cmp.Or(*duration, *sleep), *finishChapter)
+	}
+}
+
+// readLimits builds the limits of 'read' from its flags: the last chapter to read (a
+// position as 'read' takes it), a number of chapters and a reading time.
+func readLimits(text *novelText, start playback.Position, to string, chapters int, duration time.Duration, finishChapter bool) (playback.Limits, error) {
+	var limits playback.Limits
+	if to != "" {
+		pos, err := text.parse(to, start)
+		if err != nil {
+			return limits, fmt.Errorf("--to: %v", err)
+		}
+		if pos.Chapter < start.Chapter {
+			return limits, fmt.Errorf("--to chapter %d is before chapter %d, where reading starts", pos.Chapter+1, start.Chapter+1)
+		}
+		limits.EndChapter = pos.Chapter + 1
+	}
+	if chapters < 0 {
+		return limits, fmt.Errorf("--chapters must be at least 1")
+	}
+	if chapters > 0 && (limits.EndChapter == 0 || start.Chapter+chapters < limits.EndChapter) {
+		limits.EndChapter = start.Chapter + chapters
+	}
+	if duration < 0 {
+		return limits, fmt.Errorf("the reading time must be positive")
+	}
+	if finishChapter && duration == 0 {
+		return limits, fmt.Errorf("--finish-chapter needs a time from --for or --sleep")
+	}
+	limits.Duration = duration
+	limits.FinishChapter = finishChapter
+	if limits != (playback.Limits{}) {
+		limits.FadeSegments = readFadeSegments
+	}
+	return limits, nil
+}
+
+// readFrom reads the active novel aloud from start, continuing with the following
+// segments and chapters when auto_next is on or until one of limits is reached. With
+// recap, the configured recap is read first if the novel wasn't read for a while.
+func readFrom(text *novelText, start playback.Position, limits playback.Limits, recap bool) {
+	progInfo := currentProgress()
+	if progInfo.LastReadChapterIndex != start.Chapter || progInfo.LastReadSegmentIndex != start.Segment {
+		fmt.Printf("Moving to Chapter %d, Segment %d, saving progress...\n", start.Chapter+1, start.Segment)
+		progInfo.LastReadChapterIndex = start.Chapter
+		progInfo.LastReadSegmentIndex = start.Segment
+		progressDirty = true
+		saveProgress() // Save progress immediately
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelRead = cancel
+	defer func() { cancelRead = nil }()
+	if recap && !readRecap(ctx, text, start) {
+		return
+	}
+
+	engine := playback.NewEngine(text.segments)
+	engine.Speaker = &tts.Speaker{Voice: cfg.Voice, Rate: cfg.Rate}
+	engine.Filter = text.filter
+	engine.AutoNext = cfg.AutoReadNext || limits != (playback.Limits{})
+	engine.Limits = limits
+	describeLimits(text, start, limits)
+	engine.Sinks = []playback.Sink{
+		&readPrinter{text: text},
+		&progressSaver{text: text, interval: readSaveInterval},
+		playback.SinkFunc(logSession),
+	}
+	if engine.AutoNext { // Controls would make a single segment pause at the next chapter instead of stopping
+		input := newBookmarkInput()
+		engine.Controls = input.controls
+		engine.Sinks = append(engine.Sinks, input)
+		go input.listen()
+	}
+	engine.Run(ctx, start) // Errors are reported to the sinks
+}
+
+// describeLimits tells when 'read' will stop on its own.
+func describeLimits(text *novelText, start playback.Position, limits playback.Limits) {
+	var parts []string
+	if limits.EndChapter > 0 {
+		parts = append(parts, fmt.Sprintf("at the end of Chapter %d: %s", limits.EndChapter, text.titles[min(limits.EndChapter, len(text.titles))-1]))
+	}
+	if limits.Duration > 0 {
+		when := fmt.Sprintf("after %s", limits.Duration)
+		if limits.FinishChapter {
+			when += " and the end of that chapter"
+		}
+		parts = append(parts, when)
+	}
+	if len(parts) > 0 {
+		fmt.Printf("Reading from Chapter %d, Segment %d; stopping %s.\n", start.Chapter+1, start.Segment, strings.Join(parts, " or "))
+	}
+}
+
+// readPrinter shows what 'read' is doing.
+type readPrinter struct {
+	text    *novelText
+	started bool // The first chapter started
+}
+
+func (p *readPrinter) Handle(e playback.Event) {
+	switch e.Kind {
+	case playback.ChapterStarted:
+		if p.started {
+			fmt.Println("Chapter finished. Auto-reading next chapter...")
+		}
+		p.started = true
+		fmt.Printf("--- Reading Chapter %d: %s ---\n", e.Pos.Chapter+1, p.text.titles[e.Pos.Chapter])
+	case playback.SegmentStarted:
+		fmt.Printf("\n[Segment %d/%d]\n%s\n", e.Pos.Segment+1, len(p.text.segments[e.Pos.Chapter]), e.Text)
+		fmt.Println("(Speaking...)")
+	case playback.SegmentFinished:
+		fmt.Println("(Segment finished)")
+	case playback.Failed:
+		log.Printf("Error during TTS for Ch %d, Seg %d: %v", e.Pos.Chapter+1, e.Pos.Segment, e.Err)
+		fmt.Println("Use 'view' to read the text without speech.")
+	case playback.Ended:
+		switch e.Reason {
+		case playback.EndOfNovel:
+			fmt.Println("Reached the end of the novel.")
+		case playback.SingleSegment:
+			fmt.Println("Auto-next disabled. Stopping.")
+		case playback.Canceled:
+			fmt.Printf("Stopped at Chapter %d, Segment %d.\n", e.Pos.Chapter+1, e.Pos.Segment)
+		case playback.LimitReached:
+			fmt.Printf("Reading limit reached after Chapter %d, Segment %d.\n", e.Pos.Chapter+1, e.Pos.Segment)
+			progInfo := currentProgress()
+			fmt.Printf("Next time reading continues at Chapter %d, Segment %d.\n", progInfo.LastReadChapterIndex+1, progInfo.LastReadSegmentIndex)
+		}
+	}
+}
+
+// progressSaver keeps the reading position at the current segment and, once it was
+// heard to its end, at the next one, marking completed chapters on the way. It saves
+// when a new chapter starts, every interval finished segments and when a limit stops
+// reading: with save if set, otherwise with saveProgress and its messages.
+type progressSaver struct {
+	text     *novelText
+	interval int
+	save     func()
+	finished int
+	started  bool // The first chapter started
+}
+
+func (s *progressSaver) Handle(e playback.Event) {
+	switch e.Kind {
+	case playback.ChapterStarted:
+		if s.started {
+			s.flush("")
+		}
+		s.started = true
+	case playback.SegmentCued:
+		moveTo(e.Pos)
+	case playback.SegmentFinished:
+		advancePast(s.text, e.Pos)
+		s.finished++
+		if s.finished%s.interval == 0 && progressDirty {
+			s.flush(fmt.Sprintf("(Auto-saving progress after %d segments...)", s.finished))
+		}
+	case playback.Ended:
+		if e.Reason == playback.LimitReached {
+			s.flush("")
+		}
+	}
+}
+
+// flush saves progress, printing note first unless saving with save.
+func (s *progressSaver) flush(note string) {
+	if s.save != nil {
+		s.save()
+		return
+	}
+	if note != "" {
+		fmt.Println(note)
+	}
+	saveProgress()
+}
+
+// moveTo sets the active novel's reading position.
+func moveTo(pos playback.Position) {
+	progInfo := currentProgress()
+	if progInfo.LastReadChapterIndex != pos.Chapter || progInfo.LastReadSegmentIndex != pos.Segment {
+		progInfo.LastReadChapterIndex = pos.Chapter
+		progInfo.LastReadSegmentIndex = pos.Segment
+		progressDirty = true
+	}
+}
+
+// advancePast records that a segment of the active novel was heard to its end, so
+// reading continues at the next segment with text.
+func advancePast(text *novelText, pos playback.Position) {
+	markHeard(text, pos)
+	if next, err := text.stepSegments(pos, 1); err == nil {
+		moveTo(next)
+	}
+}
+
+// markHeard records that a segment of the active novel was heard to its end, and when.
+// If it is the last segment of its chapter with text, the chapter is completed.
+func markHeard(text *novelText, pos playback.Position) {
+	currentProgress().LastReadAt = time.Now()
+	progressDirty = true
+	if next, err := text.stepSegments(pos, 1); err == nil && next.Chapter == pos.Chapter {
+		return
+	}
+	if pos.Chapter < len(activeNovel.ChapterHashes) {
+		currentProgress().Complete(activeNovel.ChapterHashes[pos.Chapter])
+	}
+}
+
+// logSession logs the reading session: it starts when speaking starts or resumes,
+// counts the finished segments and is saved when reading pauses or ends. Sessions
+// cover time spent speaking only, so pauses don't lower the rate.
+func logSession(e playback.Event) {
+	switch e.Kind {
+	case playback.SegmentStarted:
+		startSession(e.Pos.Chapter, e.Pos.Segment)
+	case playback.Updated:
+		if e.State == playback.Playing {
+			startSession(e.Pos.Chapter, e.Pos.Segment)
+		} else {
+			endSession()
+		}
+	case playback.SegmentFinished:
+		recordSegment(e.Pos.Chapter, e.Pos.Segment, utf8.RuneCountInString(e.Text))
+	case playback.Failed, playback.Ended:
+		endSession()
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xqbumu/go-novel-reader/config"
+	"github.com/xqbumu/go-novel-reader/playback"
+	"github.com/xqbumu/go-novel-reader/tts"
+)
+
+// defaultRecapAfter is the break after which a recap is read if none is configured.
+const defaultRecapAfter = 12 * time.Hour
+
+// recapAfter returns the break after which a recap is read.
+func recapAfter(r config.Recap) time.Duration {
+	if d, err := time.ParseDuration(r.After); err == nil {
+		return d
+	}
+	return defaultRecapAfter
+}
+
+// recapName describes a recap setting.
+func recapName(r config.Recap) string {
+	var what string
+	switch r.Mode {
+	case config.RecapSegments:
+		what = fmt.Sprintf("re-read the last %d segments", r.Amount)
+	case config.RecapSeconds:
+		what = fmt.Sprintf("re-read the last %d seconds", r.Amount)
+	case config.RecapCue:
+		what = "say \"Previously\" with the chapter title"
+	default:
+		return "off"
+	}
+	what += fmt.Sprintf(" after a break of %s", formatDuration(recapAfter(r)))
+	if r.Rate > 0 && r.Mode != config.RecapCue {
+		what += fmt.Sprintf(", at %d wpm", r.Rate)
+	}
+	return what
+}
+
+// handleRecapConfig changes the recap read when resuming after a break.
+func handleRecapConfig(args []string) {
+	if len(args) == 0 {
+		fmt.Printf("recap: %s\n", recapName(cfg.Recap))
+		return
+	}
+	r := cfg.Recap
+	amount := func() int {
+		if len(args) < 2 {
+			log.Fatalf("Error: config recap %s requires a number.", args[0])
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			log.Fatalf("Error: Invalid number '%s'. Please give a positive whole number.", args[1])
+		}
+		return n
+	}
+	switch args[0] {
+	case "off":
+		r.Mode, r.Amount = "", 0
+	case config.RecapSegments, config.RecapSeconds:
+		r.Mode, r.Amount = args[0], amount()
+	case config.RecapCue:
+		r.Mode, r.Amount = config.RecapCue, 0
+	case "after":
+		if len(args) < 2 {
+			log.Fatal("Error: config recap after requires a duration such as 24h.")
+		}
+		d, err := time.ParseDuration(args[1])
+		if err != nil || d < 0 {
+			log.Fatalf("Error: Invalid duration '%s'. Please give a duration such as 30m, 12h or 72h.", args[1])
+		}
+		r.After = d.String()
+	case "rate":
+		r.Rate = 0 // No value reads the recap at the normal rate
+		if len(args) > 1 {
+			rate, err := strconv.Atoi(args[1])
+			if err != nil || rate < tts.MinRate || rate > tts.MaxRate {
+				log.Fatalf("Error: Invalid rate '%s'. Please give words per minute between %d and %d.", args[1], tts.MinRate, tts.MaxRate)
+			}
+			r.Rate = rate
+		}
+	default:
+		log.Fatalf("Error: Unknown recap setting '%s'. Available: off, segments <n>, seconds <n>, cue, after <duration>, rate [wpm]", args[0])
+	}
+	cfg.Recap = r
+	configDirty = true
+	fmt.Printf("Set recap to: %s\n", recapName(cfg.Recap))
+}
+
+// readRecap reads the configured recap before reading resumes at start, if the active
+// novel wasn't read for a while. It reports false if reading was canceled meanwhile.
+func readRecap(ctx context.Context, text *novelText, start playback.Position) bool {
+	r := cfg.Recap
+	lastRead := currentProgress().LastReadAt
+	if r.Mode == "" || lastRead.IsZero() || time.Since(lastRead) < recapAfter(r) {
+		return true
+	}
+
+	rate := cmp.Or(r.Rate, cfg.Rate)
+	var segments []string
+	switch r.Mode {
+	case config.RecapCue:
+		segments = []string{fmt.Sprintf("Previously: %s.", text.titles[start.Chapter])}
+	case config.RecapSegments:
+		segments = text.textBefore(start, r.Amount, 0)
+	case config.RecapSeconds:
+		charsPerMinute := speakingRate()
+		if charsPerMinute > 0 && rate > 0 {
+			// The measured rate is at the normal speaking rate; the recap may be faster.
+			charsPerMinute *= float64(rate) / float64(cmp.Or(cfg.Rate, tts.DefaultRate))
+		}
+		segments = text.textBefore(start, 1, int(charsPerMinute*float64(r.Amount)/60))
+	}
+	if len(segments) == 0 {
+		return true
+	}
+
+	fmt.Printf("--- Recap (last read %s) ---\n", lastRead.Format("2006-01-02 15:04"))
+	engine := playback.NewEngine([][]string{segments})
+	engine.Speaker = &tts.Speaker{Voice: cfg.Voice, Rate: rate}
+	engine.Filter = text.filter
+	engine.AutoNext = true
+	engine.Sinks = []playback.Sink{playback.SinkFunc(func(e playback.Event) {
+		switch e.Kind {
+		case playback.SegmentStarted:
+			fmt.Printf("\n[Recap]\n%s\n", e.Text)
+		case playback.Failed:
+			log.Printf("Error reading the recap: %v", e.Err)
+		}
+	})}
+	engine.Run(ctx, playback.Position{})
+	if ctx.Err() != nil {
+		return false
+	}
+	fmt.Println("--- End of recap ---")
+	return true
+}
+
+// textBefore returns the segments with text just before pos, in reading order: at
+// least minSegments of them and enough to have minChars characters, as far as the
+// novel goes back.
+func (t *novelText) textBefore(pos playback.Position, minSegments, minChars int) []string {
+	var segments []string
+	chars := 0
+	for len(segments) < minSegments || chars < minChars {
+		prev, err := t.stepSegments(pos, -1)
+		if err != nil {
+			break
+		}
+		pos = prev
+		segment := t.segments[pos.Chapter][pos.Segment]
+		segments = append(segments, segment)
+		chars += utf8.RuneCountInString(strings.TrimSpace(t.filter(segment)))
+	}
+	slices.Reverse(segments)
+	return segments
+}
@@ -0,0 +1,107 @@
+package screen
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Names of special keys. Other keys are the typed character itself, like "q" or "章".
+const (
+	KeyUp        = "up"
+	KeyDown      = "down"
+	KeyLeft      = "left"
+	KeyRight     = "right"
+	KeyPageUp    = "pgup"
+	KeyPageDown  = "pgdn"
+	KeyHome      = "home"
+	KeyEnd       = "end"
+	KeyEnter     = "enter"
+	KeyTab       = "tab"
+	KeyEscape    = "esc"
+	KeyBackspace = "backspace"
+	KeyCtrlC     = "ctrl+c"
+	KeyCtrlD     = "ctrl+d"
+)
+
+// escapeKeys maps the escape sequences terminals send for special keys.
+var escapeKeys = map[string]string{
+	"\x1b[A": KeyUp, "\x1b[B": KeyDown, "\x1b[C": KeyRight, "\x1b[D": KeyLeft,
+	"\x1bOA": KeyUp, "\x1bOB": KeyDown, "\x1bOC": KeyRight, "\x1bOD": KeyLeft,
+	"\x1b[5~": KeyPageUp, "\x1b[6~": KeyPageDown,
+	"\x1b[H": KeyHome, "\x1b[F": KeyEnd, "\x1b[1~": KeyHome, "\x1b[4~": KeyEnd,
+	"\x1bOH": KeyHome, "\x1bOF": KeyEnd,
+}
+
+// controlKeys maps control characters to key names.
+var controlKeys = map[byte]string{
+	'\r': KeyEnter, '\n': KeyEnter, '\t': KeyTab,
+	0x7f: KeyBackspace, 0x08: KeyBackspace,
+	0x03: KeyCtrlC, 0x04: KeyCtrlD,
+}
+
+// ReadKeys sends the keys typed on r, which should be a terminal in raw mode, until
+// it ends. Unknown escape sequences are dropped.
+func ReadKeys(r io.Reader) <-chan string {
+	keys := make(chan string)
+	go func() {
+		defer close(keys)
+		buf := make([]byte, 256)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				for _, key := range ParseKeys(string(buf[:n])) {
+					keys <- key
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return keys
+}
+
+// ParseKeys splits input read from a raw terminal into keys.
+func ParseKeys(input string) []string {
+	var keys []string
+	for input != "" {
+		if input[0] == 0x1b {
+			key, n := parseEscape(input)
+			if key != "" {
+				keys = append(keys, key)
+			}
+			input = input[n:]
+			continue
+		}
+		if name, ok := controlKeys[input[0]]; ok {
+			keys = append(keys, name)
+			input = input[1:]
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(input)
+		keys = append(keys, input[:size])
+		input = input[size:]
+	}
+	return keys
+}
+
+// parseEscape decodes the escape sequence at the start of input and returns the key
+// and the bytes it took. A lone escape is the Escape key.
+func parseEscape(input string) (string, int) {
+	for seq, key := range escapeKeys {
+		if strings.HasPrefix(input, seq) {
+			return key, len(seq)
+		}
+	}
+	if len(input) == 1 || (input[1] != '[' && input[1] != 'O') {
+		return KeyEscape, 1
+	}
+	// Skip an unknown CSI sequence: parameters up to a final byte in '@'..'~'.
+	for i := 2; i < len(input); i++ {
+		if input[i] >= '@' && input[i] <= '~' {
+			return "", i + 1
+		}
+	}
+	return "", len(input)
+}
@@ -0,0 +1,68 @@
+// Package screen draws full-screen terminal interfaces with plain ANSI escape codes:
+// raw keyboard input, the alternate screen, and text measured in terminal columns so
+// that CJK characters line up.
+package screen
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Text styles. Styled text must end with Reset.
+const (
+	Reset   = "\x1b[0m"
+	Bold    = "\x1b[1m"
+	Dim     = "\x1b[2m"
+	Reverse = "\x1b[7m"
+	Yellow  = "\x1b[33m"
+)
+
+// Screen is a terminal switched to raw mode and the alternate screen.
+type Screen struct {
+	in    *os.File
+	out   *os.File
+	state *term.State
+}
+
+// Open takes over the terminal on in and out until Close.
+func Open(in, out *os.File) (*Screen, error) {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	out.WriteString("\x1b[?1049h\x1b[?25l") // Alternate screen, hidden cursor
+	return &Screen{in: in, out: out, state: state}, nil
+}
+
+// Close gives the terminal back as it was.
+func (s *Screen) Close() error {
+	s.out.WriteString("\x1b[?25h\x1b[?1049l")
+	return term.Restore(int(s.in.Fd()), s.state)
+}
+
+// Size returns the terminal's width and height, or 80x24 if unknown.
+func (s *Screen) Size() (width, height int) {
+	width, height, err := term.GetSize(int(s.out.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// Draw replaces the screen content with lines, one per row. Lines must fit the width.
+func (s *Screen) Draw(lines []string) error {
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(line)
+		b.WriteString(Reset + "\x1b[K")
+	}
+	b.WriteString("\x1b[J") // Clear rows below
+	_, err := s.out.WriteString(b.String())
+	return err
+}
@@ -0,0 +1,102 @@
+package screen
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Width returns the number of terminal columns s takes. CJK characters take two.
+func Width(s string) int {
+	return runewidth.StringWidth(s)
+}
+
+// Truncate cuts s to at most width columns, ending in "…" if it was cut.
+func Truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	return runewidth.Truncate(s, width, "…")
+}
+
+// Pad truncates or pads s with spaces to exactly width columns.
+func Pad(s string, width int) string {
+	s = Truncate(s, width)
+	return s + strings.Repeat(" ", max(width-Width(s), 0))
+}
+
+// Wrap breaks text into lines of at most width columns. Latin words are kept whole
+// unless longer than a line; CJK text may break between any two characters.
+// Line breaks in text are kept; an empty text gives one empty line.
+func Wrap(text string, width int) []string {
+	width = max(width, 1)
+	var lines []string
+	for _, para := range strings.Split(text, "\n") {
+		lines = append(lines, wrapLine(para, width)...)
+	}
+	return lines
+}
+
+// wrapLine wraps a single line of text.
+func wrapLine(text string, width int) []string {
+	var lines []string
+	var line strings.Builder
+	lineWidth := 0
+	flush := func() {
+		lines = append(lines, strings.TrimRight(line.String(), " "))
+		line.Reset()
+		lineWidth = 0
+	}
+	for _, word := range splitWords(text) {
+		w := Width(word)
+		if lineWidth+w > width && lineWidth > 0 {
+			flush()
+			if word == " " {
+				continue // No space at the start of a wrapped line
+			}
+		}
+		for w > width { // A word longer than a line is cut anywhere
+			for _, r := range word {
+				rw := runewidth.RuneWidth(r)
+				if lineWidth+rw > width {
+					flush()
+				}
+				line.WriteRune(r)
+				lineWidth += rw
+			}
+			word, w = "", 0
+		}
+		line.WriteString(word)
+		lineWidth += w
+	}
+	flush()
+	return lines
+}
+
+// splitWords splits text into words, single spaces and single wide characters, the
+// units a line may be broken between.
+func splitWords(text string) []string {
+	var words []string
+	start := -1
+	for i, r := range text {
+		breakable := unicode.IsSpace(r) || runewidth.RuneWidth(r) > 1
+		if breakable {
+			if start >= 0 {
+				words = append(words, text[start:i])
+				start = -1
+			}
+			if unicode.IsSpace(r) {
+				words = append(words, " ")
+			} else {
+				words = append(words, string(r))
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, text[start:])
+	}
+	return words
+}
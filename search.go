@@ -0,0 +1,198 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/xqbumu/go-novel-reader/config"
+	"github.com/xqbumu/go-novel-reader/novel"
+	"github.com/xqbumu/go-novel-reader/screen"
+	"github.com/xqbumu/go-novel-reader/search"
+)
+
+// maxListedHits limits how many hits 'search' prints; --goto can still pick any hit.
+const maxListedHits = 100
+
+// snippetContext is how many characters are shown on each side of a hit's first match.
+const snippetContext = 30
+
+// novelHit is a search hit in one of the searched novels.
+type novelHit struct {
+	info     *config.NovelInfo
+	chapters []novel.Chapter
+	search.Hit
+}
+
+// handleSearch searches the active novel, or every novel with --all, and lists the
+// segments containing the query.
+func handleSearch(args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	all := fs.Bool("all", false, "search every novel in the library")
+	useRegex := fs.Bool("regex", false, "treat the query as a regular expression")
+	gotoHit := fs.Int("goto", 0, "move the reading position to hit `N`")
+	query := strings.Join(parseCommandFlags(fs, args), " ")
+	if query == "" {
+		log.Fatal("Error: search command requires a query. Usage: search <query> [--all] [--regex] [--goto N]")
+	}
+
+	m := search.Plain(query)
+	if *useRegex {
+		var err error
+		if m, err = search.Regex(query); err != nil {
+			log.Fatalf("Error: Invalid regular expression '%s': %v", query, err)
+		}
+	}
+
+	var novels []*config.NovelInfo
+	if *all {
+		novels = getNovelsSorted()
+	} else if activeNovel != nil {
+		novels = []*config.NovelInfo{activeNovel}
+	}
+	if len(novels) == 0 {
+		fmt.Println("No active novel selected. Use 'switch <novel>' first, or search every novel with --all.")
+		return
+	}
+
+	var hits []novelHit
+	for _, info := range novels {
+		chapters, found, err := searchNovel(info, m)
+		if err != nil {
+			log.Printf("Warning: Skipping '%s': %v", info.Title, err)
+			continue
+		}
+		for _, hit := range found {
+			hits = append(hits, novelHit{info: info, chapters: chapters, Hit: hit})
+		}
+	}
+	if len(hits) == 0 {
+		fmt.Printf("No matches for '%s'.\n", query)
+		return
+	}
+
+	if *gotoHit != 0 {
+		if *gotoHit < 1 || *gotoHit > len(hits) {
+			log.Fatalf("Error: --goto %d is out of range (1-%d).", *gotoHit, len(hits))
+		}
+		gotoSearchHit(hits[*gotoHit-1])
+		return
+	}
+	printHits(hits, *all)
+}
+
+// searchNovel returns a novel's chapters and the hits of m in them. The novel's index
+// is rebuilt if its file or text settings changed since it was built; chapters the
+// index rules out are not searched, and if it rules out all of them the file isn't
+// parsed at all.
+func searchNovel(info *config.NovelInfo, m search.Matcher) ([]novel.Chapter, []search.Hit, error) {
+	size, modTime, err := novelSource(info).Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	filter := filterPipeline(info).Apply
+	stamp := indexStamp(info, fmt.Sprintf("%d@%d", size, modTime.UnixNano()))
+	path := searchIndexPath(info)
+
+	var chapters []novel.Chapter
+	var segments [][]string
+	ix, ok := search.Load(path, stamp)
+	if !ok {
+		if chapters, segments, err = novelSegments(info); err != nil {
+			return nil, nil, err
+		}
+		ix = search.Build(segments, filter, stamp)
+		if err := ix.Save(path); err != nil {
+			log.Printf("Warning: Could not save the search index of '%s': %v", info.Title, err)
+		}
+	}
+
+	only, narrowed := ix.Candidates(m)
+	if narrowed && len(only) == 0 {
+		return nil, nil, nil
+	}
+	if segments == nil {
+		if chapters, segments, err = novelSegments(info); err != nil {
+			return nil, nil, err
+		}
+		if len(segments) != ix.Chapters { // Changed without a new modification time
+			only = nil
+		}
+	}
+	return chapters, search.Find(segments, filter, m, only), nil
+}
+
+// novelSegments parses a novel without printing anything and splits its chapters into
+// segments. The active novel's chapters are reused if they are loaded.
+func novelSegments(info *config.NovelInfo) ([]novel.Chapter, [][]string, error) {
+	chapters := info.Chapters
+	if len(chapters) == 0 {
+		var err error
+		if chapters, err = novel.ParseNovel(novelSource(info), novelFormat(info)); err != nil {
+			return nil, nil, err
+		}
+		novel.ReflowChapters(chapters, info.Reflow)
+	}
+	return chapters, chapterSegments(chapters), nil
+}
+
+// indexStamp describes everything the text of a novel's segments depends on: the state
+// of its file and how it is split, reflowed and filtered.
+func indexStamp(info *config.NovelInfo, fileState string) string {
+	parts := []string{fileState, info.ArchiveMember, info.SplitStrategy, info.DetectedRegex,
+		fmt.Sprint(info.ChunkSize), info.Reflow}
+	for _, name := range enabledFilters(info) {
+		if rule := findFilterRule(name); rule != nil {
+			name += "=" + rule.Pattern + "=>" + rule.Replace
+		}
+		parts = append(parts, name)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// searchIndexPath returns where a novel's search index is kept.
+func searchIndexPath(info *config.NovelInfo) string {
+	return filepath.Join(dataDir, "index", info.ID+".gob")
+}
+
+// printHits lists the hits, numbered for --goto, with the matches highlighted.
+func printHits(hits []novelHit, showNovel bool) {
+	mark := func(s string) string { return "«" + s + "»" }
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		mark = func(s string) string { return screen.Reverse + s + screen.Reset }
+	}
+	var current *config.NovelInfo
+	for i, hit := range hits[:min(len(hits), maxListedHits)] {
+		if showNovel && hit.info != current {
+			current = hit.info
+			fmt.Printf("%s: %s\n", hit.info.ID, hit.info.Title)
+		}
+		fmt.Printf("%4d. Chapter %d: %s, Segment %d\n", i+1, hit.Chapter+1, hit.chapters[hit.Chapter].Title, hit.Segment)
+		fmt.Printf("      %s\n", hit.Snippet(snippetContext, mark))
+	}
+	if len(hits) > maxListedHits {
+		fmt.Printf("... and %d more hits. Refine the query to see them.\n", len(hits)-maxListedHits)
+	}
+	fmt.Printf("%d hit(s). Use --goto N to continue reading at a hit.\n", len(hits))
+}
+
+// gotoSearchHit makes a hit's novel active and moves its reading position to the hit.
+func gotoSearchHit(hit novelHit) {
+	if cfg.ActiveNovelPath != hit.info.FilePath {
+		cfg.ActiveNovelPath = hit.info.FilePath
+		activeNovel = hit.info
+		configDirty = true
+		fmt.Printf("Switched active novel to: %s\n", hit.info.FilePath)
+	}
+	progInfo := currentProgress()
+	progInfo.LastReadChapterIndex = hit.Chapter
+	progInfo.LastReadSegmentIndex = hit.Segment
+	progressDirty = true
+	fmt.Printf("Reading position set to Chapter %d: %s, Segment %d. Use 'read' to continue there.\n",
+		hit.Chapter+1, hit.chapters[hit.Chapter].Title, hit.Segment)
+}
@@ -0,0 +1,116 @@
+package search
+
+import (
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// indexVersion changes when the index layout or tokens change; older indexes are rebuilt.
+const indexVersion = 1
+
+// Index maps the bigrams of a novel to the chapters containing them.
+type Index struct {
+	Version  int
+	Stamp    string // State of the novel's file and text settings the index was built from
+	Chapters int
+	Postings map[string][]int32 // Bigram to ascending chapter indexes, stored as gaps
+}
+
+// Build indexes the filtered text of a novel's chapter segments.
+func Build(chapters [][]string, filter func(string) string, stamp string) *Index {
+	ix := &Index{Version: indexVersion, Stamp: stamp, Chapters: len(chapters), Postings: make(map[string][]int32)}
+	last := make(map[string]int32) // Chapter a bigram was last added for
+	for c, segments := range chapters {
+		var text strings.Builder
+		for _, segment := range segments {
+			text.WriteString(filter(segment))
+			text.WriteByte('\n')
+		}
+		for bigram := range tokens(text.String()) {
+			prev := last[bigram] // 0 for the first chapter of a bigram
+			ix.Postings[bigram] = append(ix.Postings[bigram], int32(c)-prev)
+			last[bigram] = int32(c)
+		}
+	}
+	return ix
+}
+
+// Load reads the index at path. It reports false if there is none, it can't be read,
+// or it was built from another state of the novel than stamp.
+func Load(path, stamp string) (*Index, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+	var ix Index
+	if err := gob.NewDecoder(f).Decode(&ix); err != nil {
+		return nil, false
+	}
+	if ix.Version != indexVersion || ix.Stamp != stamp {
+		return nil, false
+	}
+	return &ix, true
+}
+
+// Save writes the index to path, replacing any previous one.
+func (ix *Index) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed
+	if err := gob.NewEncoder(tmp).Encode(ix); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Candidates returns the chapters that may contain matches of m, in order. It
+// reports false if m gives nothing to narrow the search down with, in which case
+// every chapter must be searched.
+func (ix *Index) Candidates(m Matcher) ([]int, bool) {
+	bigrams := m.bigrams()
+	if bigrams == nil {
+		return nil, false
+	}
+	var result []int
+	first := true
+	for bigram := range bigrams {
+		chapters := ix.chapters(bigram)
+		if first {
+			result, first = chapters, false
+		} else {
+			result = slices.DeleteFunc(result, func(c int) bool {
+				_, found := slices.BinarySearch(chapters, c)
+				return !found
+			})
+		}
+		if len(result) == 0 {
+			break
+		}
+	}
+	return result, true
+}
+
+// chapters decodes the chapter list of a bigram.
+func (ix *Index) chapters(bigram string) []int {
+	gaps := ix.Postings[bigram]
+	chapters := make([]int, len(gaps))
+	c := 0
+	for i, gap := range gaps {
+		c += int(gap)
+		chapters[i] = c
+	}
+	return chapters
+}
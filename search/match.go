@@ -0,0 +1,142 @@
+package search
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Matcher finds a query in text.
+type Matcher interface {
+	// FindAll returns the byte ranges of the non-overlapping matches in text.
+	FindAll(text string) [][2]int
+	// bigrams returns bigrams every match contains, or nil if there are none to
+	// narrow a search down with.
+	bigrams() map[string]struct{}
+}
+
+// Plain returns a Matcher for a substring, ignoring case and character width.
+func Plain(query string) Matcher {
+	folded, _ := foldString(query)
+	return plainMatcher{query: folded}
+}
+
+type plainMatcher struct {
+	query string // Folded
+}
+
+func (m plainMatcher) FindAll(text string) [][2]int {
+	if m.query == "" {
+		return nil
+	}
+	folded, offsets := foldString(text)
+	var matches [][2]int
+	for from := 0; ; {
+		i := strings.Index(folded[from:], m.query)
+		if i < 0 {
+			return matches
+		}
+		start, end := from+i, from+i+len(m.query)
+		matches = append(matches, [2]int{offsets[start], offsets[end]})
+		from = end
+	}
+}
+
+func (m plainMatcher) bigrams() map[string]struct{} {
+	set := tokens(m.query)
+	if len(set) == 0 {
+		return nil
+	}
+	return set
+}
+
+// Regex returns a Matcher for a regular expression (RE2 syntax, see
+// https://golang.org/s/re2syntax).
+func Regex(pattern string) (Matcher, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return regexMatcher{re: re}, nil
+}
+
+type regexMatcher struct {
+	re *regexp.Regexp
+}
+
+func (m regexMatcher) FindAll(text string) [][2]int {
+	var matches [][2]int
+	for _, loc := range m.re.FindAllStringIndex(text, -1) {
+		if loc[1] > loc[0] { // Empty matches mark nothing
+			matches = append(matches, [2]int{loc[0], loc[1]})
+		}
+	}
+	return matches
+}
+
+// bigrams returns nil: any text may match a regular expression.
+func (m regexMatcher) bigrams() map[string]struct{} { return nil }
+
+// Hit is a segment containing matches.
+type Hit struct {
+	Chapter int
+	Segment int
+	Text    string   // Filtered text of the segment
+	Matches [][2]int // Byte ranges of the matches in Text
+}
+
+// Find searches the segments of the given chapters, or of all chapters if only is
+// nil. filter is applied to each segment before matching.
+func Find(chapters [][]string, filter func(string) string, m Matcher, only []int) []Hit {
+	if only == nil {
+		only = make([]int, len(chapters))
+		for i := range only {
+			only[i] = i
+		}
+	}
+	var hits []Hit
+	for _, c := range only {
+		for s, segment := range chapters[c] {
+			text := strings.TrimSpace(filter(segment))
+			if matches := m.FindAll(text); len(matches) > 0 {
+				hits = append(hits, Hit{Chapter: c, Segment: s, Text: text, Matches: matches})
+			}
+		}
+	}
+	return hits
+}
+
+// Snippet returns the text around the first match of a hit, context runes on each
+// side, with the matches in it passed through mark.
+func (h Hit) Snippet(context int, mark func(string) string) string {
+	first := h.Matches[0]
+	start, end := first[0], first[1]
+	for n := 0; n < context && start > 0; n++ {
+		_, size := utf8.DecodeLastRuneInString(h.Text[:start])
+		start -= size
+	}
+	for n := 0; n < context && end < len(h.Text); n++ {
+		_, size := utf8.DecodeRuneInString(h.Text[end:])
+		end += size
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, m := range h.Matches {
+		from, to := max(m[0], start), min(m[1], end)
+		if from >= to {
+			continue
+		}
+		b.WriteString(h.Text[pos:from])
+		b.WriteString(mark(h.Text[from:to]))
+		pos = to
+	}
+	b.WriteString(h.Text[pos:end])
+	if end < len(h.Text) {
+		b.WriteString("…")
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,73 @@
+// Package search finds text in novels. Matching ignores case and the difference
+// between full-width and half-width forms, so "ＡＢＣ" finds "abc". An inverted index
+// of character bigrams narrows a search down to the chapters that can match.
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// fold normalizes a rune for matching: lower case, and full-width ASCII and the
+// ideographic space mapped to their half-width forms.
+func fold(r rune) rune {
+	switch {
+	case r >= 0xFF01 && r <= 0xFF5E:
+		r -= 0xFEE0
+	case r == 0x3000:
+		r = ' '
+	}
+	return unicode.ToLower(r)
+}
+
+// foldString normalizes s for matching and returns, for each byte of the result, the
+// offset of the byte in s it came from (plus one final entry for the end).
+func foldString(s string) (string, []int) {
+	var b strings.Builder
+	offsets := make([]int, 0, len(s)+1)
+	for i, r := range s {
+		n := b.Len()
+		b.WriteRune(fold(r))
+		for range b.Len() - n {
+			offsets = append(offsets, i)
+		}
+	}
+	offsets = append(offsets, len(s))
+	return b.String(), offsets
+}
+
+// isCJK reports whether r is written without spaces between words.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// runClass groups runes into runs: 1 for CJK, 2 for other letters and digits, 0 for
+// separators.
+func runClass(r rune) int {
+	switch {
+	case isCJK(r):
+		return 1
+	case unicode.IsLetter(r) || unicode.IsDigit(r):
+		return 2
+	}
+	return 0
+}
+
+// tokens returns the distinct bigrams of folded text: pairs of neighbouring runes
+// within a run of CJK characters or of other letters and digits. Every substring of
+// two or more runes of such a run has all its bigrams in the run, so a text can only
+// contain a query if it has all the query's bigrams.
+func tokens(text string) map[string]struct{} {
+	set := make(map[string]struct{})
+	var prev rune
+	prevClass := 0
+	for _, r := range text {
+		r = fold(r)
+		class := runClass(r)
+		if class != 0 && class == prevClass {
+			set[string([]rune{prev, r})] = struct{}{}
+		}
+		prev, prevClass = r, class
+	}
+	return set
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xqbumu/go-novel-reader/config"
+	"github.com/xqbumu/go-novel-reader/tts"
+)
+
+const (
+	statsDays  = 7 // Days listed in the daily overview
+	statsWeeks = 4 // Weeks listed in the weekly overview
+
+	// minRateSample is the listening time of a novel needed to use its own speaking
+	// rate for the time-left estimate; below it the overall rate is used.
+	minRateSample = 5 * time.Minute
+)
+
+// --- Session Logging ---
+
+// startSession begins logging a reading session of the active novel, unless one is
+// already running (auto-next continues the same session).
+func startSession(chapterIndex, segmentIndex int) {
+	if session != nil || activeNovel == nil {
+		return
+	}
+	session = &config.Session{
+		NovelID:      activeNovel.ID,
+		Start:        time.Now(),
+		StartChapter: chapterIndex,
+		StartSegment: segmentIndex,
+		EndChapter:   chapterIndex,
+		EndSegment:   segmentIndex,
+		Backend:      tts.Backend,
+		Voice:        cfg.Voice,
+	}
+}
+
+// recordSegment adds a fully spoken segment to the running session.
+func recordSegment(chapterIndex, segmentIndex, chars int) {
+	if session == nil {
+		return
+	}
+	session.End = time.Now()
+	session.EndChapter = chapterIndex
+	session.EndSegment = segmentIndex
+	session.Segments++
+	session.Chars += chars
+}
+
+// endSession logs the running session. Sessions in which nothing was spoken are
+// dropped. The session ends with its last finished segment, so an interrupted
+// segment doesn't count toward the speaking rate.
+func endSession() {
+	if session == nil {
+		return
+	}
+	s := *session
+	session = nil
+	if s.Segments == 0 {
+		return
+	}
+	if err := store.AddSession(s); err != nil {
+		log.Printf("Error saving reading session: %v", err)
+	}
+}
+
+// --- Statistics ---
+
+// listening is time spent and characters spoken.
+type listening struct {
+	time     time.Duration
+	chars    int
+	sessions int
+}
+
+func (l *listening) add(s config.Session) {
+	if d := s.End.Sub(s.Start); d > 0 {
+		l.time += d
+	}
+	l.chars += s.Chars
+	l.sessions++
+}
+
+// charsPerMinute returns the speaking rate, 0 if nothing was measured.
+func (l listening) charsPerMinute() float64 {
+	if l.time <= 0 {
+		return 0
+	}
+	return float64(l.chars) / l.time.Minutes()
+}
+
+// handleStats prints listening statistics from the reading history.
+func handleStats() {
+	sessions, err := store.Sessions(time.Time{})
+	if err != nil {
+		log.Fatalf("Error loading reading history: %v", err)
+	}
+	if len(sessions) == 0 {
+		fmt.Println("No reading sessions recorded yet. Sessions are logged while 'read' speaks.")
+		return
+	}
+
+	var total listening
+	byDay := make(map[string]*listening)
+	byNovel := make(map[string]*listening)
+	for _, s := range sessions {
+		total.add(s)
+		day := s.Start.Local().Format(time.DateOnly)
+		if byDay[day] == nil {
+			byDay[day] = &listening{}
+		}
+		byDay[day].add(s)
+		if byNovel[s.NovelID] == nil {
+			byNovel[s.NovelID] = &listening{}
+		}
+		byNovel[s.NovelID].add(s)
+	}
+
+	today := startOfDay(time.Now())
+	fmt.Println("Last 7 days:")
+	for i := statsDays - 1; i >= 0; i-- {
+		day := today.AddDate(0, 0, -i)
+		l := byDay[day.Format(time.DateOnly)]
+		if l == nil {
+			l = &listening{}
+		}
+		fmt.Printf("  %s  %8s  %7d chars\n", day.Format("Mon 2006-01-02"), formatDuration(l.time), l.chars)
+	}
+
+	fmt.Println("\nLast 4 weeks:")
+	monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	for i := statsWeeks - 1; i >= 0; i-- {
+		start := monday.AddDate(0, 0, -7*i)
+		var week listening
+		for d := 0; d < 7; d++ {
+			if l := byDay[start.AddDate(0, 0, d).Format(time.DateOnly)]; l != nil {
+				week.time += l.time
+				week.chars += l.chars
+			}
+		}
+		fmt.Printf("  Week of %s  %8s  %7d chars\n", start.Format(time.DateOnly), formatDuration(week.time), week.chars)
+	}
+
+	current, longest := streaks(byDay, today)
+	fmt.Printf("\nTotal: %s in %d sessions, %d chars, %.0f chars/min\n",
+		formatDuration(total.time), total.sessions, total.chars, total.charsPerMinute())
+	fmt.Printf("Streak: %d days (longest %d)\n", current, longest)
+
+	fmt.Println("\nPer novel:")
+	ids := make([]string, 0, len(byNovel))
+	for id := range byNovel {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b string) int { return cmp.Compare(byNovel[b].time, byNovel[a].time) })
+	for _, id := range ids {
+		l := byNovel[id]
+		fmt.Printf("  %s [%s]: %s in %d sessions, %d chars, %.0f chars/min\n",
+			novelTitle(id), id, formatDuration(l.time), l.sessions, l.chars, l.charsPerMinute())
+	}
+
+	if activeNovel != nil {
+		printTimeLeft(novelRate(total, byNovel[activeNovel.ID]))
+	}
+}
+
+// novelRate returns a novel's speaking rate if enough of it was listened to, otherwise
+// the overall rate.
+func novelRate(total listening, novel *listening) float64 {
+	if novel != nil && novel.time >= minRateSample {
+		return novel.charsPerMinute()
+	}
+	return total.charsPerMinute()
+}
+
+// speakingRate returns the active novel's speaking rate in characters per minute
+// measured from the reading history, 0 if nothing was measured yet.
+func speakingRate() float64 {
+	sessions, err := store.Sessions(time.Time{})
+	if err != nil {
+		return 0
+	}
+	var total, novel listening
+	for _, s := range sessions {
+		total.add(s)
+		if s.NovelID == activeNovel.ID {
+			novel.add(s)
+		}
+	}
+	return novelRate(total, &novel)
+}
+
+// printTimeLeft estimates how long it takes to finish the active novel at rate.
+func printTimeLeft(rate float64) {
+	loadActiveNovelChapters()
+	progInfo, ok := progressData[activeNovel.FilePath]
+	if !ok || len(activeNovel.Chapters) == 0 || rate <= 0 {
+		return
+	}
+	chars := 0
+	for i := max(progInfo.LastReadChapterIndex, 0); i < len(activeNovel.Chapters); i++ {
+		content := activeNovel.Chapters[i].Content
+		if i == progInfo.LastReadChapterIndex {
+			segments := segmentSeparator.Split(content, -1)
+			for j := max(progInfo.LastReadSegmentIndex, 0); j < len(segments); j++ {
+				chars += utf8.RuneCountInString(segments[j])
+			}
+			continue
+		}
+		chars += utf8.RuneCountInString(content)
+	}
+	left := time.Duration(float64(chars) / rate * float64(time.Minute))
+	fmt.Printf("\nTime left for '%s': about %s (%d chars at %.0f chars/min)\n",
+		activeNovel.Title, formatDuration(left), chars, rate)
+}
+
+// streaks returns the current and longest runs of consecutive days with listening.
+// The current streak still counts if today has no listening yet.
+func streaks(byDay map[string]*listening, today time.Time) (current, longest int) {
+	days := make([]string, 0, len(byDay))
+	for day := range byDay {
+		days = append(days, day)
+	}
+	slices.Sort(days)
+	run := 0
+	var prev time.Time
+	for _, day := range days {
+		t, err := time.ParseInLocation(time.DateOnly, day, time.Local)
+		if err != nil {
+			continue
+		}
+		if run > 0 && t.Equal(prev.AddDate(0, 0, 1)) {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+		prev = t
+	}
+	if prev.Equal(today) || prev.Equal(today.AddDate(0, 0, -1)) {
+		current = run
+	}
+	return current, longest
+}
+
+// startOfDay returns midnight of t's day in local time.
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Local().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+// novelTitle returns the title of the novel with the given ID, or the ID if the
+// novel was removed from the library.
+func novelTitle(id string) string {
+	for _, info := range cfg.Novels {
+		if info.ID == id {
+			return info.Title
+		}
+	}
+	return "(removed)"
+}
+
+// formatDuration formats d as hours and minutes, like "2h 05m" or "12m".
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+}
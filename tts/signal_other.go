@@ -0,0 +1,13 @@
+//go:build !unix
+
+package tts
+
+import (
+	"errors"
+	"os"
+)
+
+var errNoPause = errors.New("pausing speech is not supported on this system")
+
+func suspend(p *os.Process) error { return errNoPause }
+func resume(p *os.Process) error  { return errNoPause }
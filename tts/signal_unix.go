@@ -0,0 +1,12 @@
+//go:build unix
+
+package tts
+
+import (
+	"os"
+	"syscall"
+)
+
+// suspend stops the process with SIGSTOP; resume continues it with SIGCONT.
+func suspend(p *os.Process) error { return p.Signal(syscall.SIGSTOP) }
+func resume(p *os.Process) error  { return p.Signal(syscall.SIGCONT) }
@@ -4,13 +4,34 @@ import (
 	"fmt"
 	"os/exec"
 	"runtime"
+	"strconv"
 )
 
-// SpeakAsync starts the macOS 'say' command asynchronously to read the given text aloud.
-// It returns a channel that will receive an error if the command fails to start or finish,
-// or nil if it completes successfully. The channel will be closed upon completion or error.
-// Returns an immediate error if the OS is not macOS or text is empty.
-func SpeakAsync(text string) (<-chan error, error) {
+// Backend names the speech engine used, for reading history.
+const Backend = "say"
+
+// Speaking rates in words per minute, for 'say -r'.
+const (
+	DefaultRate = 180 // Roughly the rate of the system voices
+	MinRate     = 80
+	MaxRate     = 500
+)
+
+// Speaker speaks text with a voice and rate and can stop, pause and resume it.
+type Speaker struct {
+	Voice  string  // 'say' voice; empty uses the system voice
+	Rate   int     // Words per minute; 0 uses the voice's default rate
+	Volume float64 // Volume from 0 to 1 relative to the system volume; 0 means full volume
+}
+
+// Utterance is text being spoken by a Speaker.
+type Utterance struct {
+	cmd  *exec.Cmd
+	done chan error
+}
+
+// Start starts speaking text and returns at once.
+func (s *Speaker) Start(text string) (*Utterance, error) {
 	if runtime.GOOS != "darwin" {
 		return nil, fmt.Errorf("TTS functionality is only supported on macOS")
 	}
@@ -18,41 +39,47 @@ func SpeakAsync(text string) (<-chan error, error) {
 		return nil, fmt.Errorf("cannot speak empty text")
 	}
 
-	cmd := exec.Command("say", text)
-	err := cmd.Start() // Start the command asynchronously
-	if err != nil {
+	var args []string
+	if s.Voice != "" {
+		args = append(args, "-v", s.Voice)
+	}
+	if s.Rate > 0 {
+		args = append(args, "-r", strconv.Itoa(s.Rate))
+	}
+	if s.Volume > 0 && s.Volume < 1 {
+		text = fmt.Sprintf("[[volm %.2f]] %s", s.Volume, text) // Embedded speech command
+	}
+	cmd := exec.Command("say", append(args, text)...)
+	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("failed to start 'say' command: %w", err)
 	}
-
-	doneChan := make(chan error, 1) // Buffered channel to avoid blocking sender
-
-	// Goroutine to wait for the command to finish
+	u := &Utterance{cmd: cmd, done: make(chan error, 1)}
 	go func() {
-		defer close(doneChan)
-		waitErr := cmd.Wait() // Wait for the command to complete
-		if waitErr != nil {
-			doneChan <- fmt.Errorf("'say' command finished with error: %w", waitErr)
-		} else {
-			doneChan <- nil // Signal successful completion
+		if err := cmd.Wait(); err != nil {
+			u.done <- fmt.Errorf("'say' command finished with error: %w", err)
 		}
+		close(u.done)
 	}()
+	return u, nil
+}
 
-	return doneChan, nil // Return the channel for the caller to wait on
+// Done returns a channel that receives an error if speaking failed and is closed
+// when speaking ends. A stopped utterance ends with an error.
+func (u *Utterance) Done() <-chan error {
+	return u.done
 }
 
-// Speak runs the 'say' command synchronously (waits for completion).
-// This is kept for simplicity if async behavior is not needed.
-func Speak(text string) error {
-	doneChan, err := SpeakAsync(text)
-	if err != nil {
-		return err // Error starting the command
-	}
-	// Wait for the command to finish and get the result
-	err = <-doneChan
-	return err // Return the error from cmd.Wait() or nil
+// Stop stops speaking at once, also when paused.
+func (u *Utterance) Stop() error {
+	return u.cmd.Process.Kill()
+}
+
+// Pause suspends speaking where it is.
+func (u *Utterance) Pause() error {
+	return suspend(u.cmd.Process)
 }
 
-// TODO: Implement a way to stop ongoing speech.
-// This would require storing the *exec.Cmd process associated with SpeakAsync
-// and calling cmd.Process.Kill() or sending a signal. This adds complexity
-// managing the process lifecycle.
+// Resume continues speaking after Pause.
+func (u *Utterance) Resume() error {
+	return resume(u.cmd.Process)
+}
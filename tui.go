@@ -0,0 +1,844 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+
+	"github.com/xqbumu/go-novel-reader/config"
+	"github.com/xqbumu/go-novel-reader/novel"
+	"github.com/xqbumu/go-novel-reader/playback"
+	"github.com/xqbumu/go-novel-reader/screen"
+	"github.com/xqbumu/go-novel-reader/tts"
+)
+
+// tuiHelp lists the keys of the TUI in its header.
+const tuiHelp = "Tab pane · ↑/↓ move · Enter open · space play/pause · ←/→ segment · [/] chapter · +/- speed · r replay · b bookmark · / search · s settings · q quit"
+
+// tuiPane is a pane of the TUI that keys move in.
+type tuiPane int
+
+const (
+	paneLibrary tuiPane = iota
+	paneChapters
+	paneReading
+	paneCount
+)
+
+// tuiEvent is something the player reported. Events are handled on the UI goroutine,
+// so the player's goroutine never touches the library or progress.
+type tuiEvent struct {
+	player *playback.Player // Events of a player that was replaced are dropped
+	event  playback.Event
+	state  playback.State
+	rate   int
+	read   int // Characters before the current segment
+	total  int // Characters in the novel
+}
+
+// chapterRow is a line of the chapter tree: a volume or a chapter.
+type chapterRow struct {
+	index  int // Chapter index
+	volume bool
+	depth  int
+}
+
+// textLine is a line of a chapter wrapped for the screen.
+type textLine struct {
+	text    string
+	segment int // Segment the line belongs to, -1 for spacing
+}
+
+// tui is the full-screen terminal interface: library, chapter tree and reading pane.
+type tui struct {
+	scr     *screen.Screen
+	focus   tuiPane
+	message string
+	quit    bool
+
+	novels    []*config.NovelInfo
+	libCursor int
+
+	volumes       []bool // Whether each chapter title is a volume heading
+	collapsed     map[int]bool
+	rows          []chapterRow
+	chapterCursor int
+
+	segments [][]string // Segments of the active novel's chapters
+	filter   func(string) string
+	pos      playback.Position
+	state    playback.State
+	rate     int
+	read     int
+	total    int
+	history  float64 // Characters per minute from the reading history
+
+	lines      []textLine
+	linesKey   [2]int // Chapter and width the lines were wrapped for
+	scroll     int
+	follow     bool // Keep the spoken segment in view
+	pageHeight int  // Rows of the focused list, for paging
+
+	player     *playback.Player
+	progress   *progressSaver // Keeps the progress of the player's novel
+	commands   chan playback.Command
+	playerDone chan struct{}
+
+	mu     sync.Mutex
+	events []tuiEvent
+	wake   chan struct{}
+
+	prompt   string // Prompt shown in the bottom line while typing, "" otherwise
+	input    string
+	onSubmit func(string)
+
+	settings       bool // Settings shown in the reading pane
+	settingsCursor int
+	query          string // Last search
+}
+
+// handleTUI runs the full-screen interface until the user quits.
+func handleTUI() {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		log.Fatal("Error: 'tui' needs an interactive terminal.")
+	}
+	if !acquireReaderLock() {
+		return
+	}
+	scr, err := screen.Open(os.Stdin, os.Stdout)
+	if err != nil {
+		log.Fatalf("Error switching the terminal to raw mode: %v", err)
+	}
+	// Messages of the shared helpers would be written over the screen; the TUI shows
+	// its own instead.
+	stdout := os.Stdout
+	if devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0); err == nil {
+		os.Stdout = devNull
+		defer devNull.Close()
+	}
+	log.SetOutput(io.Discard)
+	restoreTerminal = func() {
+		scr.Close()
+		os.Stdout = stdout
+		log.SetOutput(os.Stderr)
+	}
+
+	t := &tui{scr: scr, collapsed: make(map[int]bool), wake: make(chan struct{}, 1), follow: true}
+	if activeNovel == nil {
+		t.message = "Pick a novel in the library and press Enter."
+	} else {
+		t.focus = paneReading
+	}
+	t.loadNovel()
+	t.run()
+	t.stopPlayer()
+	restoreTerminal()
+	restoreTerminal = nil
+}
+
+// run handles keys and player events until the user quits.
+func (t *tui) run() {
+	keys := screen.ReadKeys(os.Stdin)
+	ticker := time.NewTicker(time.Second) // Picks up terminal resizes and the ETA
+	defer ticker.Stop()
+	for !t.quit {
+		t.draw()
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				return
+			}
+			t.handleKey(key)
+		case <-t.wake:
+			t.handleEvents()
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleEvents handles the queued events of the player.
+func (t *tui) handleEvents() {
+	t.mu.Lock()
+	events := t.events
+	t.events = nil
+	t.mu.Unlock()
+	for _, e := range events {
+		t.handleEvent(e)
+	}
+}
+
+// --- Novel and Player ---
+
+// loadNovel loads the active novel's chapters and cues its last read segment.
+func (t *tui) loadNovel() {
+	t.novels = getNovelsSorted()
+	for i, info := range t.novels {
+		if info == activeNovel {
+			t.libCursor = i
+		}
+	}
+	t.segments, t.rows, t.lines = nil, nil, nil
+	t.pos = playback.Position{} // A position of the previous novel may not exist in this one
+	t.linesKey = [2]int{-1, -1}
+	if activeNovel == nil {
+		return
+	}
+	loadActiveNovelChapters()
+	if len(activeNovel.Chapters) == 0 {
+		t.message = fmt.Sprintf("Could not load '%s'. Use 'relocate' if it was moved.", activeNovel.Title)
+		return
+	}
+	t.segments = chapterSegments(activeNovel.Chapters)
+	t.volumes = make([]bool, len(activeNovel.Chapters))
+	for i, chapter := range activeNovel.Chapters {
+		t.volumes[i] = novel.TitleKind(chapter.Title) == novel.KindVolume
+	}
+	clear(t.collapsed)
+	t.buildRows()
+	t.filter = filterPipeline(activeNovel).Apply
+	t.history = speakingRate()
+	text := &novelText{segments: t.segments, filter: t.filter}
+	t.pos = text.saved(currentProgress())
+	t.startPlayer(t.pos, true)
+}
+
+// switchNovel makes info the active novel.
+func (t *tui) switchNovel(info *config.NovelInfo) {
+	if info == activeNovel {
+		t.focus = paneReading
+		return
+	}
+	t.stopPlayer()
+	t.saveQuietly()
+	cfg.ActiveNovelPath = info.FilePath
+	activeNovel = info
+	configDirty = true
+	t.loadNovel()
+	if t.segments != nil {
+		t.focus = paneReading
+		t.message = fmt.Sprintf("Switched to '%s'. Press space to play.", info.Title)
+	}
+}
+
+// startPlayer starts a player for the active novel at pos, paused or speaking.
+func (t *tui) startPlayer(pos playback.Position, paused bool) {
+	p := playback.New(t.segments, pos)
+	p.Speaker = &tts.Speaker{Voice: cfg.Voice, Rate: cfg.Rate}
+	p.Filter = t.filter
+	p.AutoNext = cfg.AutoReadNext
+	p.Paused = paused
+
+	// The sink runs on the player's goroutine and only queues events; the sinks shared
+	// with 'read' and 'play' get them in handleEvent.
+	p.Sinks = []playback.Sink{playback.SinkFunc(func(e playback.Event) {
+		read, total := p.Progress()
+		t.post(tuiEvent{player: p, event: e, state: p.State(), rate: p.Speaker.Rate, read: read, total: total})
+	})}
+	t.progress = &progressSaver{
+		text:     &novelText{segments: t.segments, filter: t.filter},
+		interval: playSaveInterval,
+		save:     t.saveQuietly,
+	}
+
+	commands := make(chan playback.Command)
+	done := make(chan struct{})
+	t.player, t.commands, t.playerDone = p, commands, done
+	t.state = playback.Paused
+	go func() {
+		p.Run(commands) // Errors are reported to the sink
+		close(done)
+	}()
+}
+
+// stopPlayer stops the player and waits until it stopped speaking.
+func (t *tui) stopPlayer() {
+	if t.player == nil {
+		return
+	}
+	select {
+	case t.commands <- playback.Command{Action: playback.Quit}:
+	case <-t.playerDone:
+	}
+	<-t.playerDone
+	t.handleEvents() // The player's last events, such as the progress it stopped at
+	t.player = nil
+	endSession()
+}
+
+// playerRunning reports whether the player still takes commands.
+func (t *tui) playerRunning() bool {
+	if t.player == nil {
+		return false
+	}
+	select {
+	case <-t.playerDone:
+		return false
+	default:
+		return true
+	}
+}
+
+// send passes a command to the player, starting a paused one at the current
+// position if it has ended.
+func (t *tui) send(cmd playback.Command) {
+	if t.segments == nil {
+		t.message = "No novel loaded. Pick one in the library."
+		return
+	}
+	if !t.playerRunning() {
+		t.stopPlayer()
+		t.startPlayer(t.pos, true)
+	}
+	select {
+	case t.commands <- cmd:
+	case <-t.playerDone:
+	}
+}
+
+// restartPlayer applies changed settings by starting a new player where the old one was.
+func (t *tui) restartPlayer() {
+	if t.segments == nil {
+		return
+	}
+	playing := t.state == playback.Playing
+	t.stopPlayer()
+	t.startPlayer(t.pos, !playing)
+}
+
+// post queues an event for the UI goroutine. It never blocks.
+func (t *tui) post(e tuiEvent) {
+	t.mu.Lock()
+	t.events = append(t.events, e)
+	t.mu.Unlock()
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// handleEvent applies an event of the player to the library, progress and screen.
+func (t *tui) handleEvent(e tuiEvent) {
+	if e.player != t.player {
+		return
+	}
+	t.state, t.read, t.total = e.state, e.read, e.total
+	if e.rate != cfg.Rate {
+		cfg.Rate = e.rate
+		configDirty = true
+	}
+	t.rate = e.rate
+
+	switch e.event.Kind {
+	case playback.SegmentCued:
+		t.pos = e.event.Pos
+		t.follow = true
+		if t.focus != paneChapters {
+			t.chapterCursor = t.rowOf(e.event.Pos.Chapter)
+		}
+	case playback.Marked:
+		if b, err := saveBookmark("", "", e.event.Pos.Chapter, e.event.Pos.Segment); err != nil {
+			t.message = "Error: " + err.Error()
+		} else {
+			t.message = fmt.Sprintf("Bookmarked '%s'.", b.Name)
+		}
+	case playback.Failed:
+		t.message = "Speech stopped: " + e.event.Err.Error()
+	case playback.Ended:
+		if e.event.Reason == playback.EndOfNovel {
+			t.message = "Reached the end of the novel."
+		}
+	}
+	t.progress.Handle(e.event)
+	logSession(e.event)
+}
+
+// saveQuietly saves changed progress without printing.
+func (t *tui) saveQuietly() {
+	if !progressDirty {
+		return
+	}
+	if err := store.SaveProgress(progressData); err != nil {
+		t.message = "Error saving progress: " + err.Error()
+		return
+	}
+	progressDirty = false
+}
+
+// --- Keys ---
+
+// handleKey applies a key to the prompt, the settings or the focused pane.
+func (t *tui) handleKey(key string) {
+	if t.prompt != "" {
+		t.handlePromptKey(key)
+		return
+	}
+	t.message = ""
+	if t.settings {
+		t.handleSettingsKey(key)
+		return
+	}
+	switch key {
+	case "q", screen.KeyCtrlC, screen.KeyCtrlD:
+		t.quit = true
+	case screen.KeyTab:
+		t.focus = (t.focus + 1) % paneCount
+	case screen.KeyUp, "k":
+		t.moveCursor(-1)
+	case screen.KeyDown, "j":
+		t.moveCursor(1)
+	case screen.KeyPageUp:
+		t.moveCursor(-max(t.pageHeight-1, 1))
+	case screen.KeyPageDown:
+		t.moveCursor(max(t.pageHeight-1, 1))
+	case screen.KeyHome:
+		t.moveCursor(-1 << 30)
+	case screen.KeyEnd:
+		t.moveCursor(1 << 30)
+	case screen.KeyEnter:
+		t.open()
+	case "c":
+		t.toggleVolume()
+	case " ":
+		t.send(playback.Command{Action: playback.TogglePause})
+	case screen.KeyLeft, "h":
+		t.send(playback.Command{Action: playback.PrevSegment})
+	case screen.KeyRight, "l":
+		t.send(playback.Command{Action: playback.NextSegment})
+	case "[":
+		t.send(playback.Command{Action: playback.PrevChapter})
+	case "]":
+		t.send(playback.Command{Action: playback.NextChapter})
+	case "r":
+		t.send(playback.Command{Action: playback.Replay})
+	case "+", "=":
+		t.send(playback.Command{Action: playback.Faster})
+	case "-", "_":
+		t.send(playback.Command{Action: playback.Slower})
+	case "b":
+		t.send(playback.Command{Action: playback.Bookmark})
+	case "/":
+		t.ask("Search: ", "", func(query string) { t.search(query, 1) })
+	case "n":
+		t.search(t.query, 1)
+	case "N":
+		t.search(t.query, -1)
+	case "s":
+		t.settings = true
+	}
+}
+
+// moveCursor moves the cursor of the focused pane, or scrolls the reading pane.
+func (t *tui) moveCursor(delta int) {
+	switch t.focus {
+	case paneLibrary:
+		t.libCursor = clampIndex(t.libCursor+delta, len(t.novels))
+	case paneChapters:
+		t.chapterCursor = clampIndex(t.chapterCursor+delta, len(t.rows))
+	case paneReading:
+		t.follow = false
+		t.scroll = max(t.scroll+delta, 0) // Limited when drawn
+	}
+}
+
+// open acts on the line under the cursor: switches novels or jumps to a chapter.
+func (t *tui) open() {
+	switch t.focus {
+	case paneLibrary:
+		if t.libCursor < len(t.novels) {
+			t.switchNovel(t.novels[t.libCursor])
+		}
+	case paneChapters:
+		if t.chapterCursor < len(t.rows) {
+			t.send(playback.Command{Action: playback.Seek, To: playback.Position{Chapter: t.rows[t.chapterCursor].index, Segment: -1}})
+		}
+	}
+}
+
+// toggleVolume folds or unfolds the volume under the chapter cursor.
+func (t *tui) toggleVolume() {
+	if t.focus != paneChapters || t.chapterCursor >= len(t.rows) {
+		return
+	}
+	volume := t.rows[t.chapterCursor].index
+	for volume >= 0 && !t.volumes[volume] {
+		volume--
+	}
+	if volume < 0 {
+		return
+	}
+	t.collapsed[volume] = !t.collapsed[volume]
+	t.buildRows()
+	t.chapterCursor = t.rowOf(volume)
+}
+
+// ask shows a prompt in the bottom line; submit gets the typed text on Enter.
+func (t *tui) ask(prompt, input string, submit func(string)) {
+	t.prompt, t.input, t.onSubmit = prompt, input, submit
+}
+
+func (t *tui) handlePromptKey(key string) {
+	switch key {
+	case screen.KeyEnter:
+		submit, input := t.onSubmit, t.input
+		t.prompt, t.input, t.onSubmit = "", "", nil
+		submit(input)
+	case screen.KeyEscape, screen.KeyCtrlC:
+		t.prompt, t.input, t.onSubmit = "", "", nil
+	case screen.KeyBackspace:
+		if _, size := utf8.DecodeLastRuneInString(t.input); size > 0 {
+			t.input = t.input[:len(t.input)-size]
+		}
+	default:
+		if utf8.RuneCountInString(key) == 1 && key >= " " {
+			t.input += key
+		}
+	}
+}
+
+// Rows of the settings list.
+const (
+	settingAutoNext = iota
+	settingRate
+	settingVoice
+	settingCount
+)
+
+func (t *tui) handleSettingsKey(key string) {
+	switch key {
+	case screen.KeyEscape, "s", "q":
+		t.settings = false
+	case screen.KeyUp, "k":
+		t.settingsCursor = clampIndex(t.settingsCursor-1, settingCount)
+	case screen.KeyDown, "j":
+		t.settingsCursor = clampIndex(t.settingsCursor+1, settingCount)
+	case screen.KeyEnter, screen.KeyLeft, screen.KeyRight, "h", "l", " ":
+		switch t.settingsCursor {
+		case settingAutoNext:
+			cfg.AutoReadNext = !cfg.AutoReadNext
+			configDirty = true
+			t.restartPlayer()
+		case settingRate:
+			action := playback.Faster
+			if key == screen.KeyLeft || key == "h" {
+				action = playback.Slower
+			}
+			if t.segments != nil {
+				t.send(playback.Command{Action: action})
+			}
+		case settingVoice:
+			t.ask("Voice (empty for the system voice): ", cfg.Voice, func(voice string) {
+				cfg.Voice = strings.TrimSpace(voice)
+				configDirty = true
+				t.restartPlayer()
+			})
+		}
+	}
+}
+
+// search cues the next segment after (dir 1) or before (dir -1) the current one that
+// contains query.
+func (t *tui) search(query string, dir int) {
+	if query == "" || t.segments == nil {
+		return
+	}
+	t.query = query
+	pos, ok := findSegment(t.segments, t.filter, t.pos, query, dir)
+	if !ok {
+		t.message = fmt.Sprintf("'%s' not found.", query)
+		return
+	}
+	t.send(playback.Command{Action: playback.Seek, To: pos})
+	t.message = fmt.Sprintf("Found '%s' in chapter %d, segment %d. n/N for the next/previous match.", query, pos.Chapter+1, pos.Segment+1)
+}
+
+// findSegment returns the first segment after (dir 1) or before (dir -1) from whose
+// filtered text contains query, ignoring case, wrapping around the novel.
+func findSegment(segments [][]string, filter func(string) string, from playback.Position, query string, dir int) (playback.Position, bool) {
+	needle := strings.ToLower(query)
+	count := 0
+	for _, chapter := range segments {
+		count += len(chapter)
+	}
+	pos := from
+	for range count {
+		pos.Segment += dir
+		for pos.Segment < 0 || pos.Segment >= len(segments[pos.Chapter]) {
+			pos.Chapter = (pos.Chapter + dir + len(segments)) % len(segments)
+			if dir > 0 {
+				pos.Segment = 0
+			} else {
+				pos.Segment = len(segments[pos.Chapter]) - 1
+			}
+		}
+		if strings.Contains(strings.ToLower(filter(segments[pos.Chapter][pos.Segment])), needle) {
+			return pos, true
+		}
+	}
+	return from, false
+}
+
+// --- Drawing ---
+
+// draw redraws the whole screen.
+func (t *tui) draw() {
+	width, height := t.scr.Size()
+	if width < 40 || height < 8 {
+		t.scr.Draw([]string{screen.Truncate("Terminal too small. q quits.", width)})
+		return
+	}
+	leftWidth := min(max(width/3, 20), 40)
+	rightWidth := width - leftWidth - 1
+	bodyHeight := height - 2
+	libHeight := max(bodyHeight/3, 3)
+
+	left := t.drawLibrary(leftWidth, libHeight)
+	left = append(left, t.drawChapters(leftWidth, bodyHeight-libHeight)...)
+	var right []string
+	if t.settings {
+		right = t.drawSettings(rightWidth, bodyHeight)
+	} else {
+		right = t.drawReading(rightWidth, bodyHeight)
+	}
+	switch t.focus {
+	case paneLibrary:
+		t.pageHeight = libHeight - 1
+	case paneChapters:
+		t.pageHeight = bodyHeight - libHeight - 1
+	default:
+		t.pageHeight = bodyHeight - 1
+	}
+
+	title := "go-novel-reader"
+	if activeNovel != nil {
+		title += " │ " + activeNovel.Title
+	}
+	lines := []string{styled(screen.Pad(" "+title+" │ "+tuiHelp, width), screen.Reverse)}
+	for i := range bodyHeight {
+		lines = append(lines, left[i]+screen.Dim+"│"+screen.Reset+right[i])
+	}
+	lines = append(lines, t.drawStatusLine(width))
+	t.scr.Draw(lines)
+}
+
+// drawLibrary draws the novels with a progress bar each.
+func (t *tui) drawLibrary(width, height int) []string {
+	lines := []string{t.paneTitle(fmt.Sprintf("Library (%d)", len(t.novels)), paneLibrary, width)}
+	top := listTop(t.libCursor, len(t.novels), height-1)
+	for i := top; i < len(t.novels) && len(lines) < height; i++ {
+		info := t.novels[i]
+		marker := "  "
+		if info == activeNovel {
+			marker = "* "
+		}
+		const barWidth = 8
+		percent := t.novelPercent(info)
+		filled := int(percent * barWidth / 100)
+		bar := strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
+		line := screen.Pad(marker+info.Title, width-barWidth-6) + " " + bar + fmt.Sprintf(" %3d%%", int(percent))
+		lines = append(lines, t.cursorStyle(line, i == t.libCursor, paneLibrary))
+	}
+	return fillLines(lines, width, height)
+}
+
+// novelPercent returns how much of a novel was read, by characters for the active
+// novel and by chapters for the others, whose text isn't loaded.
+func (t *tui) novelPercent(info *config.NovelInfo) float64 {
+	if info == activeNovel && t.total > 0 {
+		return float64(t.read) * 100 / float64(t.total)
+	}
+	progInfo, ok := progressData[info.FilePath]
+	if !ok || len(info.ChapterTitles) == 0 {
+		return 0
+	}
+	if progInfo.Status(info.ChapterHashes) == config.StatusFinished {
+		return 100
+	}
+	return float64(min(progInfo.LastReadChapterIndex, len(info.ChapterTitles))) * 100 / float64(len(info.ChapterTitles))
+}
+
+// drawChapters draws the chapter tree: volumes with their chapters indented.
+func (t *tui) drawChapters(width, height int) []string {
+	lines := []string{t.paneTitle("Chapters (c folds a volume)", paneChapters, width)}
+	top := listTop(t.chapterCursor, len(t.rows), height-1)
+	for i := top; i < len(t.rows) && len(lines) < height; i++ {
+		row := t.rows[i]
+		marker := " "
+		if row.index == t.pos.Chapter {
+			marker = "▶"
+		}
+		fold := ""
+		if row.volume {
+			fold = "▾ "
+			if t.collapsed[row.index] {
+				fold = "▸ "
+			}
+		}
+		line := screen.Pad(fmt.Sprintf("%s%s%s%d %s", marker, strings.Repeat("  ", row.depth), fold, row.index+1, activeNovel.Chapters[row.index].Title), width)
+		lines = append(lines, t.cursorStyle(line, i == t.chapterCursor, paneChapters))
+	}
+	return fillLines(lines, width, height)
+}
+
+// drawReading draws the current chapter with the spoken segment highlighted.
+func (t *tui) drawReading(width, height int) []string {
+	if t.segments == nil {
+		return fillLines([]string{t.paneTitle("Reading", paneReading, width)}, width, height)
+	}
+	chapter := activeNovel.Chapters[t.pos.Chapter]
+	lines := []string{t.paneTitle(fmt.Sprintf("Chapter %d: %s", t.pos.Chapter+1, chapter.Title), paneReading, width)}
+	if t.linesKey != [2]int{t.pos.Chapter, width} {
+		t.wrapChapter(width)
+	}
+
+	visible := height - 1
+	if t.follow {
+		for i, line := range t.lines {
+			if line.segment == t.pos.Segment {
+				t.scroll = i - visible/3 // Leave some of what was read above
+				break
+			}
+		}
+	}
+	t.scroll = min(max(t.scroll, 0), max(len(t.lines)-visible, 0))
+	for _, line := range t.lines[t.scroll:min(t.scroll+visible, len(t.lines))] {
+		text := " " + screen.Pad(line.text, width-1)
+		if line.segment == t.pos.Segment {
+			text = styled(text, screen.Bold+screen.Yellow)
+		}
+		lines = append(lines, text)
+	}
+	return fillLines(lines, width, height)
+}
+
+// wrapChapter wraps the current chapter's segments for the reading pane.
+func (t *tui) wrapChapter(width int) {
+	t.lines = wrapSegments(t.segments[t.pos.Chapter], t.filter, width-2)
+	t.linesKey = [2]int{t.pos.Chapter, width}
+}
+
+// wrapSegments wraps the filtered text of a chapter's segments to width columns, with
+// an empty line after each. Segments without text are left out.
+func wrapSegments(segments []string, filter func(string) string, width int) []textLine {
+	var lines []textLine
+	for i, segment := range segments {
+		text := strings.TrimSpace(filter(segment))
+		if text == "" {
+			continue
+		}
+		for _, line := range screen.Wrap(text, width) {
+			lines = append(lines, textLine{text: line, segment: i})
+		}
+		lines = append(lines, textLine{segment: -1})
+	}
+	return lines
+}
+
+// drawSettings draws the settings list in place of the reading pane.
+func (t *tui) drawSettings(width, height int) []string {
+	autoNext := "off"
+	if cfg.AutoReadNext {
+		autoNext = "on"
+	}
+	values := []string{
+		settingAutoNext: fmt.Sprintf("auto_next  %s   (continue into the next chapter)", autoNext),
+		settingRate:     fmt.Sprintf("rate       %s   (←/→ slower/faster)", rateName(cfg.Rate)),
+		settingVoice:    fmt.Sprintf("voice      %s", cmp.Or(cfg.Voice, "(system default)")),
+	}
+	lines := []string{t.paneTitle("Settings — ↑/↓ select, Enter/←/→ change, Esc close", paneReading, width), strings.Repeat(" ", width)}
+	for i, value := range values {
+		lines = append(lines, t.cursorStyle(screen.Pad("  "+value, width), i == t.settingsCursor, paneReading))
+	}
+	return fillLines(lines, width, height)
+}
+
+// drawStatusLine draws the prompt, a message or the playback status.
+func (t *tui) drawStatusLine(width int) string {
+	switch {
+	case t.prompt != "":
+		return screen.Pad(t.prompt+t.input+"█", width)
+	case t.message != "":
+		return styled(screen.Pad(t.message, width), screen.Yellow)
+	case t.segments != nil:
+		return screen.Pad(playStatus(t.pos, t.read, t.total, t.state, t.rate, sessionRate(t.history)), width)
+	}
+	return ""
+}
+
+// paneTitle draws the title row of a pane, highlighted when it has the focus.
+func (t *tui) paneTitle(title string, pane tuiPane, width int) string {
+	if t.focus == pane {
+		return styled(screen.Pad(" "+title, width), screen.Bold+screen.Reverse)
+	}
+	return styled(screen.Pad(" "+title, width), screen.Bold)
+}
+
+// cursorStyle highlights the line under the cursor of the focused pane.
+func (t *tui) cursorStyle(line string, selected bool, pane tuiPane) string {
+	if selected && t.focus == pane {
+		return styled(line, screen.Reverse)
+	}
+	return line
+}
+
+// buildRows builds the chapter tree rows, leaving out chapters of folded volumes.
+func (t *tui) buildRows() {
+	t.rows = t.rows[:0]
+	volume := -1
+	for i, isVolume := range t.volumes {
+		switch {
+		case isVolume:
+			volume = i
+			t.rows = append(t.rows, chapterRow{index: i, volume: true})
+		case volume < 0:
+			t.rows = append(t.rows, chapterRow{index: i})
+		case !t.collapsed[volume]:
+			t.rows = append(t.rows, chapterRow{index: i, depth: 1})
+		}
+	}
+}
+
+// rowOf returns the row showing a chapter, or its folded volume.
+func (t *tui) rowOf(chapter int) int {
+	best := 0
+	for i, row := range t.rows {
+		if row.index > chapter {
+			break
+		}
+		best = i
+	}
+	return best
+}
+
+// styled wraps text, which must not change the width, in a style.
+func styled(text, style string) string {
+	return style + text + screen.Reset
+}
+
+// fillLines pads lines with empty rows to height.
+func fillLines(lines []string, width, height int) []string {
+	for len(lines) < height {
+		lines = append(lines, strings.Repeat(" ", width))
+	}
+	return lines[:height]
+}
+
+// listTop returns the first row to show so that cursor is visible in height rows.
+func listTop(cursor, count, height int) int {
+	if height <= 0 || count <= height {
+		return 0
+	}
+	return min(max(cursor-height/2, 0), count-height)
+}
+
+// clampIndex limits i to the indexes of a list of n items.
+func clampIndex(i, n int) int {
+	return max(min(i, n-1), 0)
+}
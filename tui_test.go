@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xqbumu/go-novel-reader/config"
+	"github.com/xqbumu/go-novel-reader/novel"
+	"github.com/xqbumu/go-novel-reader/playback"
+)
+
+// loadedNovel returns a novel whose chapters are loaded, with segments lines each.
+func loadedNovel(path string, chapters, segments int) *config.NovelInfo {
+	info := &config.NovelInfo{ID: filepath.Base(path), Title: filepath.Base(path), FilePath: path}
+	for c := range chapters {
+		var lines []string
+		for s := range segments {
+			lines = append(lines, fmt.Sprintf("Chapter %d, segment %d.", c+1, s))
+		}
+		title := fmt.Sprintf("Chapter %d", c+1)
+		info.Chapters = append(info.Chapters, novel.Chapter{Title: title, Content: strings.Join(lines, "\n")})
+		info.ChapterTitles = append(info.ChapterTitles, title)
+	}
+	return info
+}
+
+// useLibrary makes novels the library, with long the active novel, until the test ends.
+func useLibrary(t *testing.T, long *config.NovelInfo, novels ...*config.NovelInfo) {
+	t.Helper()
+	s, err := config.OpenStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldStore, oldCfg, oldProgress, oldActive := store, cfg, progressData, activeNovel
+	t.Cleanup(func() { store, cfg, progressData, activeNovel = oldStore, oldCfg, oldProgress, oldActive })
+	store, cfg, progressData, activeNovel = s, &config.AppConfig{Novels: map[string]*config.NovelInfo{}}, config.ProgressData{}, long
+	for _, info := range append(novels, long) {
+		cfg.Novels[info.FilePath] = info
+	}
+}
+
+func TestTUISwitchToShorterNovel(t *testing.T) {
+	tests := []struct {
+		name  string
+		short *config.NovelInfo
+		saved *config.ProgressInfo // Progress of the short novel
+		want  playback.Position
+	}{
+		{name: "not read yet", short: loadedNovel("/novels/short.txt", 2, 3)},
+		{
+			name:  "saved position",
+			short: loadedNovel("/novels/short.txt", 2, 3),
+			saved: &config.ProgressInfo{LastReadChapterIndex: 1, LastReadSegmentIndex: 2},
+			want:  playback.Position{Chapter: 1, Segment: 2},
+		},
+		{
+			name:  "saved position no longer in the novel",
+			short: loadedNovel("/novels/short.txt", 2, 3),
+			saved: &config.ProgressInfo{LastReadChapterIndex: 1, LastReadSegmentIndex: 7},
+		},
+		{name: "novel fails to load", short: &config.NovelInfo{ID: "gone", Title: "gone", FilePath: filepath.Join(t.TempDir(), "gone.txt")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			long := loadedNovel("/novels/long.txt", 20, 40)
+			useLibrary(t, long, tt.short)
+			progressData[long.FilePath] = &config.ProgressInfo{LastReadChapterIndex: 15, LastReadSegmentIndex: 30}
+			if tt.saved != nil {
+				progressData[tt.short.FilePath] = tt.saved
+			}
+
+			ui := &tui{collapsed: make(map[int]bool), wake: make(chan struct{}, 1), follow: true}
+			ui.loadNovel()
+			defer ui.stopPlayer()
+			if want := (playback.Position{Chapter: 15, Segment: 30}); ui.pos != want {
+				t.Fatalf("position of the long novel = %v, want %v", ui.pos, want)
+			}
+
+			ui.switchNovel(tt.short)
+			if ui.pos != tt.want {
+				t.Errorf("position after switching = %v, want %v", ui.pos, tt.want)
+			}
+			// Drawn before the new player reported anything.
+			ui.drawReading(60, 20)
+			ui.drawChapters(30, 10)
+			ui.drawStatusLine(60)
+		})
+	}
+}
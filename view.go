@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/xqbumu/go-novel-reader/config"
+	"github.com/xqbumu/go-novel-reader/playback"
+	"github.com/xqbumu/go-novel-reader/screen"
+)
+
+// viewHelp lists the keys of the pager.
+const viewHelp = "↑/↓ line · space/b page · ←/→ chapter · g/G top/end · / search · n/N next/prev match · q quit"
+
+// pager shows the active novel's chapters in the terminal without speaking. The
+// segment at the top of the page is the reading position.
+type pager struct {
+	scr      *screen.Screen
+	segments [][]string
+	filter   func(string) string
+	progInfo *config.ProgressInfo
+
+	chapter int
+	lines   []textLine
+	width   int // Width the lines were wrapped for
+	top     int // First line shown
+
+	query   string
+	match   *regexp.Regexp // Highlights the last search
+	prompt  bool           // Typing a search
+	input   string
+	message string
+	quit    bool
+}
+
+// handleView pages through the active novel from the last read position.
+func handleView() {
+	if activeNovel == nil {
+		fmt.Println("No active novel selected. Use 'switch <novel>' first.")
+		return
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		log.Fatal("Error: 'view' needs an interactive terminal.")
+	}
+	loadActiveNovelChapters()
+	if len(activeNovel.Chapters) == 0 {
+		fmt.Printf("Chapters not loaded for '%s'.\n", activeNovel.FilePath)
+		return
+	}
+
+	text := activeText()
+	p := &pager{segments: text.segments, filter: text.filter, progInfo: currentProgress()}
+	start := text.saved(p.progInfo)
+
+	scr, err := screen.Open(os.Stdin, os.Stdout)
+	if err != nil {
+		log.Fatalf("Error switching the terminal to raw mode: %v", err)
+	}
+	restoreTerminal = func() { scr.Close() }
+	p.scr = scr
+	p.showChapter(start.Chapter, start.Segment)
+	p.run()
+	restoreTerminal()
+	restoreTerminal = nil
+	fmt.Printf("Stopped at Chapter %d, Segment %d.\n", p.progInfo.LastReadChapterIndex+1, p.progInfo.LastReadSegmentIndex)
+}
+
+// run handles keys until the user quits.
+func (p *pager) run() {
+	keys := screen.ReadKeys(os.Stdin)
+	ticker := time.NewTicker(500 * time.Millisecond) // Picks up terminal resizes
+	defer ticker.Stop()
+	for !p.quit {
+		p.draw()
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				return
+			}
+			p.handleKey(key)
+			p.savePosition()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *pager) handleKey(key string) {
+	if p.prompt {
+		switch key {
+		case screen.KeyEnter:
+			p.prompt = false
+			p.search(p.input, 1)
+		case screen.KeyEscape, screen.KeyCtrlC:
+			p.prompt = false
+		case screen.KeyBackspace:
+			runes := []rune(p.input)
+			if len(runes) > 0 {
+				p.input = string(runes[:len(runes)-1])
+			}
+		default:
+			if len([]rune(key)) == 1 && key >= " " {
+				p.input += key
+			}
+		}
+		return
+	}
+	p.message = ""
+	page := max(p.pageHeight()-1, 1)
+	switch key {
+	case "q", screen.KeyCtrlC, screen.KeyCtrlD:
+		p.quit = true
+	case screen.KeyDown, "j", screen.KeyEnter:
+		p.scroll(1)
+	case screen.KeyUp, "k":
+		p.scroll(-1)
+	case " ", screen.KeyPageDown, "f":
+		if p.top+p.pageHeight() >= len(p.lines) {
+			p.nextChapter() // Keep paging into the next chapter
+		} else {
+			p.scroll(page)
+		}
+	case "b", screen.KeyPageUp:
+		if p.top == 0 && p.chapter > 0 {
+			p.showChapter(p.chapter-1, -1)
+		} else {
+			p.scroll(-page)
+		}
+	case screen.KeyRight, "]", "l":
+		p.nextChapter()
+	case screen.KeyLeft, "[", "h":
+		if p.chapter > 0 {
+			p.showChapter(p.chapter-1, 0)
+		}
+	case "g", screen.KeyHome:
+		p.top = 0
+	case "G", screen.KeyEnd:
+		p.scroll(len(p.lines))
+	case "/":
+		p.prompt, p.input = true, ""
+	case "n":
+		p.search(p.query, 1)
+	case "N":
+		p.search(p.query, -1)
+	}
+}
+
+// nextChapter shows the next chapter, if any.
+func (p *pager) nextChapter() {
+	if p.chapter+1 < len(p.segments) {
+		p.showChapter(p.chapter+1, 0)
+	} else {
+		p.message = "End of the novel."
+	}
+}
+
+// showChapter wraps a chapter and scrolls to a segment; -1 shows the chapter's end.
+func (p *pager) showChapter(chapter, segment int) {
+	width, _ := p.scr.Size()
+	p.chapter, p.width = chapter, width
+	p.lines = wrapSegments(p.segments[chapter], p.filter, width-2)
+	p.top = 0
+	if segment < 0 {
+		p.scroll(len(p.lines))
+		return
+	}
+	for i, line := range p.lines {
+		if line.segment >= segment {
+			p.top = i
+			break
+		}
+	}
+	p.scroll(0)
+}
+
+// scroll moves the page by delta lines within the chapter.
+func (p *pager) scroll(delta int) {
+	p.top = min(max(p.top+delta, 0), max(len(p.lines)-p.pageHeight(), 0))
+}
+
+// pageHeight returns the rows showing text: all but the title and status lines.
+func (p *pager) pageHeight() int {
+	_, height := p.scr.Size()
+	return max(height-2, 1)
+}
+
+// topSegment returns the segment at the top of the page.
+func (p *pager) topSegment() int {
+	for _, line := range p.lines[min(p.top, len(p.lines)):] {
+		if line.segment >= 0 {
+			return line.segment
+		}
+	}
+	return 0
+}
+
+// savePosition records the page as the reading position shared with 'read' and 'play'.
+// It is saved on exit.
+func (p *pager) savePosition() {
+	segment := p.topSegment()
+	if p.progInfo.LastReadChapterIndex != p.chapter || p.progInfo.LastReadSegmentIndex != segment {
+		p.progInfo.LastReadChapterIndex = p.chapter
+		p.progInfo.LastReadSegmentIndex = segment
+		progressDirty = true
+	}
+}
+
+// search shows the next segment after (dir 1) or before (dir -1) the top one that
+// contains query, and highlights the matches.
+func (p *pager) search(query string, dir int) {
+	if query == "" {
+		return
+	}
+	p.query = query
+	p.match = regexp.MustCompile("(?i)" + regexp.QuoteMeta(query))
+	from := playback.Position{Chapter: p.chapter, Segment: p.topSegment()}
+	pos, ok := findSegment(p.segments, p.filter, from, query, dir)
+	if !ok {
+		p.message = fmt.Sprintf("'%s' not found.", query)
+		return
+	}
+	p.showChapter(pos.Chapter, pos.Segment)
+	p.message = fmt.Sprintf("Found '%s' in chapter %d, segment %d.", query, pos.Chapter+1, pos.Segment+1)
+}
+
+// draw redraws the page.
+func (p *pager) draw() {
+	width, height := p.scr.Size()
+	if width != p.width {
+		p.showChapter(p.chapter, p.topSegment()) // Rewrap for the new width
+	}
+	title := fmt.Sprintf(" %s — Chapter %d/%d: %s", activeNovel.Title, p.chapter+1, len(p.segments), activeNovel.Chapters[p.chapter].Title)
+	lines := []string{screen.Reverse + screen.Pad(title, width) + screen.Reset}
+	for _, line := range p.lines[p.top:min(p.top+p.pageHeight(), len(p.lines))] {
+		text := screen.Pad(line.text, width-2)
+		if p.match != nil {
+			text = p.match.ReplaceAllString(text, screen.Reverse+"$0"+screen.Reset)
+		}
+		lines = append(lines, " "+text)
+	}
+	for len(lines) < height-1 {
+		lines = append(lines, "")
+	}
+
+	var status string
+	switch {
+	case p.prompt:
+		status = screen.Truncate("Search: "+p.input+"█", width)
+	case p.message != "":
+		status = screen.Yellow + screen.Truncate(p.message, width) + screen.Reset
+	default:
+		percent := 100
+		if len(p.lines) > 0 {
+			percent = min(p.top+p.pageHeight(), len(p.lines)) * 100 / len(p.lines)
+		}
+		status = screen.Truncate(fmt.Sprintf("Seg %d/%d | %d%% of chapter | %s", p.topSegment()+1, len(p.segments[p.chapter]), percent, viewHelp), width)
+	}
+	lines = append(lines, status)
+	p.scr.Draw(lines)
+}